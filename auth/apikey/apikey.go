@@ -0,0 +1,69 @@
+// Package apikey 静态 API key 认证
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+
+	"github.com/cr-mao/lori/auth"
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/transport"
+)
+
+var _ auth.Authenticator = (*Authenticator)(nil)
+
+// Option API key 认证选项
+type Option func(*Authenticator)
+
+// WithHeader 读取 key 的请求头, 默认 X-API-Key
+func WithHeader(header string) Option {
+	return func(a *Authenticator) {
+		a.header = header
+	}
+}
+
+type entry struct {
+	sum  [sha256.Size]byte
+	name string
+}
+
+// Authenticator 静态 API key 认证, 认证通过后 Claims 为 {"sub": name}
+type Authenticator struct {
+	header string
+	keys   []entry
+}
+
+// New keys 为 key -> 名字(调用方), 名字作为 Claims 的 sub
+func New(keys map[string]string, opts ...Option) *Authenticator {
+	a := &Authenticator{header: auth.APIKeyHeader}
+	for _, opt := range opts {
+		opt(a)
+	}
+	for key, name := range keys {
+		a.keys = append(a.keys, entry{sum: sha256.Sum256([]byte(key)), name: name})
+	}
+	return a
+}
+
+// Authenticate 比较 key 的 sha256, 所有 key 都比较一遍, 耗时和匹配的位置无关
+func (a *Authenticator) Authenticate(_ context.Context, header transport.Header) (auth.Claims, error) {
+	key := header.Get(a.header)
+	if key == "" {
+		return nil, errors.WrapC(auth.ErrMissingCredentials, auth.ErrUnauthenticated, "missing %s header", a.header)
+	}
+	sum := sha256.Sum256([]byte(key))
+	var (
+		name    string
+		matched bool
+	)
+	for _, e := range a.keys {
+		if subtle.ConstantTimeCompare(sum[:], e.sum[:]) == 1 {
+			name, matched = e.name, true
+		}
+	}
+	if !matched {
+		return nil, errors.WithCode(auth.ErrUnauthenticated, "invalid api key")
+	}
+	return auth.Claims{"sub": name}, nil
+}
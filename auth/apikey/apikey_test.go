@@ -0,0 +1,29 @@
+package apikey
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cr-mao/lori/auth"
+	"github.com/cr-mao/lori/errors"
+	lhttp "github.com/cr-mao/lori/transport/http"
+)
+
+func TestAuthenticate(t *testing.T) {
+	a := New(map[string]string{"key-1": "billing", "key-2": "report"}, WithHeader("X-Token"))
+	header := http.Header{}
+	h := lhttp.NewHeaderCarrier(header)
+
+	if _, err := a.Authenticate(context.Background(), h); !errors.Is(err, auth.ErrMissingCredentials) {
+		t.Errorf("expect missing credentials, got %v", err)
+	}
+	header.Set("X-Token", "key-2")
+	if claims, err := a.Authenticate(context.Background(), h); err != nil || claims.Subject() != "report" {
+		t.Errorf("unexpected claims %v %v", claims, err)
+	}
+	header.Set("X-Token", "key-3")
+	if _, err := a.Authenticate(context.Background(), h); !errors.IsCode(err, auth.ErrUnauthenticated) || errors.Is(err, auth.ErrMissingCredentials) {
+		t.Errorf("expect invalid api key, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Package auth 认证, 服务端从 transport.Transporter.RequestHeader 读取凭证, 认证通过后把 Claims 放入 context,
+// 客户端把凭证写到请求头; JWT 见 auth/jwt, 静态 API key 见 auth/apikey
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/transport"
+)
+
+// ErrUnauthenticated 没有凭证或者凭证无效
+const ErrUnauthenticated = 100401
+
+// 请求头
+const (
+	AuthorizationHeader = "Authorization"
+	APIKeyHeader        = "X-API-Key"
+	BearerPrefix        = "Bearer "
+)
+
+func init() {
+	errors.Register(errors.NewReasonCoder(ErrUnauthenticated, http.StatusUnauthorized, "UNAUTHENTICATED", "Unauthenticated", ""))
+}
+
+// ErrMissingCredentials 请求中没有对应的凭证, Any 遇到这个错误时尝试下一个 Authenticator
+var ErrMissingCredentials = errors.New("missing credentials")
+
+// Claims 认证通过后的身份信息, JWT 为 payload, API key 为 {"sub": key 的名字}
+type Claims map[string]interface{}
+
+// Subject sub 字段
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+// Authenticator 从请求头中读取并校验凭证, 失败时返回 ErrUnauthenticated
+type Authenticator interface {
+	Authenticate(ctx context.Context, header transport.Header) (Claims, error)
+}
+
+// AuthenticatorFunc 函数形式的 Authenticator
+type AuthenticatorFunc func(ctx context.Context, header transport.Header) (Claims, error)
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, header transport.Header) (Claims, error) {
+	return f(ctx, header)
+}
+
+// Any 依次尝试, 请求中没有某种凭证时尝试下一个, 比如同时支持 JWT 和 API key
+func Any(authenticators ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, header transport.Header) (Claims, error) {
+		for _, a := range authenticators {
+			claims, err := a.Authenticate(ctx, header)
+			if errors.Is(err, ErrMissingCredentials) {
+				continue
+			}
+			return claims, err
+		}
+		return nil, errors.WrapC(ErrMissingCredentials, ErrUnauthenticated, "missing credentials")
+	})
+}
+
+type claimsKey struct{}
+
+// NewContext 把 Claims 放入 context
+func NewContext(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// FromContext 认证通过后的 Claims
+func FromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/example/proto"
+	"github.com/cr-mao/lori/transport"
+	lgrpc "github.com/cr-mao/lori/transport/grpc"
+	lhttp "github.com/cr-mao/lori/transport/http"
+)
+
+// tokenAuthenticator Bearer good 认证通过
+var tokenAuthenticator = AuthenticatorFunc(func(ctx context.Context, header transport.Header) (Claims, error) {
+	switch header.Get(AuthorizationHeader) {
+	case "":
+		return nil, errors.WrapC(ErrMissingCredentials, ErrUnauthenticated, "missing bearer token")
+	case BearerPrefix + "good":
+		return Claims{"sub": "user-1"}, nil
+	}
+	return nil, errors.WithCode(ErrUnauthenticated, "invalid token")
+})
+
+var keyAuthenticator = AuthenticatorFunc(func(ctx context.Context, header transport.Header) (Claims, error) {
+	if header.Get(APIKeyHeader) == "" {
+		return nil, errors.WrapC(ErrMissingCredentials, ErrUnauthenticated, "missing api key")
+	}
+	return Claims{"sub": "service"}, nil
+})
+
+func TestGin(t *testing.T) {
+	s := lhttp.NewServer(lhttp.WithMode(gin.TestMode))
+	s.Use(Gin(Any(tokenAuthenticator, keyAuthenticator), WithAllowList("/healthz", "/public/*")))
+	handler := func(c *gin.Context) {
+		claims, _ := FromContext(c.Request.Context())
+		c.String(http.StatusOK, claims.Subject())
+	}
+	s.GET("/user", handler)
+	s.GET("/healthz", handler)
+	s.GET("/public/*path", handler)
+
+	tests := []struct {
+		path   string
+		header map[string]string
+		code   int
+		body   string
+	}{
+		{"/user", map[string]string{AuthorizationHeader: "Bearer good"}, http.StatusOK, "user-1"},
+		{"/user", map[string]string{APIKeyHeader: "key"}, http.StatusOK, "service"},
+		{"/user", nil, http.StatusUnauthorized, ""},
+		{"/user", map[string]string{AuthorizationHeader: "Bearer bad", APIKeyHeader: "key"}, http.StatusUnauthorized, ""},
+		{"/healthz", nil, http.StatusOK, ""},
+		{"/public/a/b", nil, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		for k, v := range tt.header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != tt.code || (tt.code == http.StatusOK && w.Body.String() != tt.body) {
+			t.Errorf("%s %v: unexpected response %d %s", tt.path, tt.header, w.Code, w.Body)
+		}
+	}
+}
+
+type greeter struct {
+	proto.UnimplementedGreeterServer
+}
+
+func (g *greeter) SayHello(ctx context.Context, r *proto.HelloRequest) (*proto.HelloResponse, error) {
+	claims, _ := FromContext(ctx)
+	return &proto.HelloResponse{Message: "hello " + claims.Subject()}, nil
+}
+
+func TestGRPC(t *testing.T) {
+	srv := lgrpc.NewServer(lgrpc.WithAddress("127.0.0.1:0"),
+		lgrpc.WithUnaryInterceptor(UnaryServerInterceptor(tokenAuthenticator, WithAllowList("/grpc.health.v1.Health/*"))))
+	proto.RegisterGreeterServer(srv, &greeter{})
+	e, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	defer srv.Stop(context.Background())
+
+	dial := func(creds Credentials) proto.GreeterClient {
+		opts := []lgrpc.ClientOption{lgrpc.WithClientEndpoint("direct:///" + e.Host), lgrpc.WithClientEnableTracing(false)}
+		if creds != nil {
+			opts = append(opts, lgrpc.WithClientUnaryInterceptor(UnaryClientInterceptor(creds)))
+		}
+		conn, err := lgrpc.DialInsecure(context.Background(), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return proto.NewGreeterClient(conn)
+	}
+
+	resp, err := dial(Bearer("good")).SayHello(context.Background(), &proto.HelloRequest{Name: "lori"})
+	if err != nil || resp.Message != "hello user-1" {
+		t.Fatalf("unexpected reply %v %v", resp, err)
+	}
+	if _, err = dial(nil).SayHello(context.Background(), &proto.HelloRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expect UNAUTHENTICATED, got %v", err)
+	}
+	if _, err = dial(Bearer("bad")).SayHello(context.Background(), &proto.HelloRequest{}); !errors.IsCode(errors.FromGrpcError(err), ErrUnauthenticated) {
+		t.Errorf("expect code %d, got %v", ErrUnauthenticated, err)
+	}
+}
+
+func TestRoundTripper(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get(APIKeyHeader)))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: RoundTripper(nil, APIKey("key-1"))}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body := make([]byte, 16)
+	n, _ := resp.Body.Read(body)
+	if string(body[:n]) != "key-1" || req.Header.Get(APIKeyHeader) != "" {
+		t.Errorf("unexpected body %q, original request header %v", body[:n], req.Header)
+	}
+}
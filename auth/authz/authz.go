@@ -0,0 +1,304 @@
+// Package authz 基于 auth 认证后的身份做授权, 支持 RBAC 和 ABAC 策略, 策略文件可以热加载,
+// 拒绝的请求通过 log 记录审计日志
+package authz
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/cr-mao/lori/auth"
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/log"
+	"github.com/cr-mao/lori/transport"
+)
+
+// ErrPermissionDenied 没有权限
+const ErrPermissionDenied = 100403
+
+func init() {
+	errors.Register(errors.NewReasonCoder(ErrPermissionDenied, http.StatusForbidden, "PERMISSION_DENIED", "Permission denied", ""))
+}
+
+// 策略的效果
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// RolePrefix 策略的 subjects 中以 role: 开头的表示角色
+const RolePrefix = "role:"
+
+// AnonymousSubject 策略的 subjects 中表示没有认证信息的请求(比如 auth.WithAllowList 中的接口),
+// 匿名请求只匹配包含它的策略, "*" 和角色都不匹配匿名请求
+const AnonymousSubject = "@anonymous"
+
+// Policy 一条策略, 所有字段都支持 * 通配, 比如 /lori.admin.v1.Admin/*
+type Policy struct {
+	ID string `json:"id"`
+	// Subjects 用户(Claims 的 sub)或者角色 role:admin
+	Subjects []string `json:"subjects"`
+	// Resources grpc 为 /package.Service/Method, http 为路由模板 /v1/user/:id
+	Resources []string `json:"resources"`
+	// Methods http 方法, 为空时不限制; grpc 请求的方法为空, 只匹配为空或者 * 的策略
+	Methods []string `json:"methods"`
+	// Conditions ABAC, key 为 Claims 中的属性, 属性为数组时任意一个匹配即可
+	Conditions map[string]string `json:"conditions"`
+	// Effect allow 或者 deny, deny 优先
+	Effect string `json:"effect"`
+}
+
+// PolicySet 策略文件的内容
+type PolicySet struct {
+	// Roles 角色 -> 成员(Claims 的 sub), 成员支持 * 通配; Claims 中的 roles/role 也作为角色
+	Roles    map[string][]string `json:"roles"`
+	Policies []Policy            `json:"policies"`
+}
+
+// Validate 校验策略
+func (s *PolicySet) Validate() error {
+	if s == nil {
+		return errors.New("policy set is nil")
+	}
+	for i, p := range s.Policies {
+		if p.Effect != EffectAllow && p.Effect != EffectDeny {
+			return fmt.Errorf("policy %d (%s): invalid effect %q", i, p.ID, p.Effect)
+		}
+		if len(p.Subjects) == 0 || len(p.Resources) == 0 {
+			return fmt.Errorf("policy %d (%s): subjects and resources are required", i, p.ID)
+		}
+	}
+	return nil
+}
+
+// Request 授权请求
+type Request struct {
+	// Anonymous 没有认证信息, 只匹配 subjects 中包含 AnonymousSubject 的策略
+	Anonymous bool
+	Subject   string
+	Roles     []string
+	Kind      transport.Kind
+	Method    string
+	Operation string
+	Claims    auth.Claims
+}
+
+// Decision 授权结果, Policy 为命中的策略 id
+type Decision struct {
+	Allowed bool
+	Policy  string
+	Reason  string
+}
+
+// Option 授权选项
+type Option func(*Enforcer)
+
+// WithAuditLogger 审计日志, 默认 log.GetLogger()
+func WithAuditLogger(logger log.Logger) Option {
+	return func(e *Enforcer) {
+		e.logger = logger
+	}
+}
+
+// WithAuditAllowed 通过的请求也记录审计日志, 默认只记录拒绝的请求
+func WithAuditAllowed(audit bool) Option {
+	return func(e *Enforcer) {
+		e.auditAllowed = audit
+	}
+}
+
+// Enforcer 授权引擎, 策略可以通过 Load 或者策略文件热加载替换, 并发安全
+type Enforcer struct {
+	set          atomic.Pointer[PolicySet]
+	logger       log.Logger
+	auditAllowed bool
+
+	// 策略文件热加载, 见 NewFileEnforcer
+	watcher *watcher
+}
+
+// NewEnforcer 使用给定的策略创建
+func NewEnforcer(set *PolicySet, opts ...Option) (*Enforcer, error) {
+	e := newEnforcer(opts)
+	if err := e.Load(set); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func newEnforcer(opts []Option) *Enforcer {
+	e := &Enforcer{}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Load 校验并替换策略
+func (e *Enforcer) Load(set *PolicySet) error {
+	if err := set.Validate(); err != nil {
+		return err
+	}
+	e.set.Store(set)
+	return nil
+}
+
+// Decide 没有命中任何策略时拒绝, 同时命中 allow 和 deny 时拒绝
+func (e *Enforcer) Decide(r Request) Decision {
+	set := e.set.Load()
+	roles := e.roles(set, r)
+	var allow *Policy
+	for i := range set.Policies {
+		p := &set.Policies[i]
+		if !p.match(r, roles) {
+			continue
+		}
+		if p.Effect == EffectDeny {
+			return Decision{Policy: p.ID, Reason: "denied by policy"}
+		}
+		if allow == nil {
+			allow = p
+		}
+	}
+	if allow == nil {
+		return Decision{Reason: "no matching policy"}
+	}
+	return Decision{Allowed: true, Policy: allow.ID}
+}
+
+// Enforce 拒绝时记录审计日志并返回 ErrPermissionDenied
+func (e *Enforcer) Enforce(ctx context.Context, r Request) error {
+	d := e.Decide(r)
+	if d.Allowed && !e.auditAllowed {
+		return nil
+	}
+	subject := r.Subject
+	if r.Anonymous {
+		subject = AnonymousSubject
+	}
+	level, msg := log.LevelWarn, "authz denied"
+	if d.Allowed {
+		level, msg = log.LevelInfo, "authz allowed"
+	}
+	logger := e.logger
+	if logger == nil {
+		logger = log.GetLogger()
+	}
+	_ = log.WithContext(ctx, logger).Log(level,
+		log.DefaultMessageKey, msg,
+		"subject", subject,
+		"roles", strings.Join(r.Roles, ","),
+		"kind", r.Kind.String(),
+		"method", r.Method,
+		"operation", r.Operation,
+		"policy", d.Policy,
+		"reason", d.Reason,
+	)
+	if d.Allowed {
+		return nil
+	}
+	return errors.WithCode(ErrPermissionDenied, "%s is not allowed to access %s %s: %s", subject, r.Method, r.Operation, d.Reason)
+}
+
+// roles 请求中的角色加上策略文件中绑定的角色
+func (e *Enforcer) roles(set *PolicySet, r Request) []string {
+	if r.Anonymous {
+		return nil
+	}
+	roles := append([]string(nil), r.Roles...)
+	for role, members := range set.Roles {
+		if matchAny(members, r.Subject) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+func (p *Policy) match(r Request, roles []string) bool {
+	if !p.matchSubject(r, roles) || !matchAny(p.Resources, r.Operation) {
+		return false
+	}
+	if len(p.Methods) > 0 && !matchAny(p.Methods, r.Method) {
+		return false
+	}
+	for key, pattern := range p.Conditions {
+		if !matchClaim(pattern, r.Claims[key]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Policy) matchSubject(r Request, roles []string) bool {
+	for _, s := range p.Subjects {
+		if r.Anonymous || s == AnonymousSubject {
+			if r.Anonymous && s == AnonymousSubject {
+				return true
+			}
+			continue
+		}
+		if role := strings.TrimPrefix(s, RolePrefix); role != s {
+			for _, name := range roles {
+				if match(role, name) {
+					return true
+				}
+			}
+		} else if match(s, r.Subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchClaim 属性为数组时任意一个匹配即可, 没有该属性时不匹配
+func matchClaim(pattern string, v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return false
+	case []interface{}:
+		for _, item := range value {
+			if match(pattern, fmt.Sprint(item)) {
+				return true
+			}
+		}
+		return false
+	}
+	return match(pattern, fmt.Sprint(v))
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if match(p, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// match 通配符匹配, * 匹配任意字符(包括 /), 其他字符精确匹配
+func match(pattern, s string) bool {
+	p, n := 0, 0
+	star, mark := -1, 0
+	for n < len(s) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, n
+			p++
+		case p < len(pattern) && pattern[p] == s[n]:
+			p++
+			n++
+		case star >= 0:
+			p = star + 1
+			mark++
+			n = mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
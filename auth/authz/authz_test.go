@@ -0,0 +1,245 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+
+	"github.com/cr-mao/lori/auth"
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/log"
+	"github.com/cr-mao/lori/transport"
+	lhttp "github.com/cr-mao/lori/transport/http"
+)
+
+var testPolicies = &PolicySet{
+	Roles: map[string][]string{"admin": {"alice", "ops-*"}},
+	Policies: []Policy{
+		{ID: "admin-all", Subjects: []string{"role:admin"}, Resources: []string{"/lori.admin.v1.Admin/*"}, Effect: EffectAllow},
+		{ID: "no-drop", Subjects: []string{"ops-*"}, Resources: []string{"/lori.admin.v1.Admin/Drop*"}, Effect: EffectDeny},
+		{ID: "user-read", Subjects: []string{"*"}, Resources: []string{"/v1/user/:id"}, Methods: []string{"GET"}, Effect: EffectAllow},
+		{ID: "tenant", Subjects: []string{"role:editor"}, Resources: []string{"/v1/doc/*"}, Methods: []string{"PUT", "POST"},
+			Conditions: map[string]string{"tenant": "acme", "scope": "doc:*"}, Effect: EffectAllow},
+	},
+}
+
+func TestDecide(t *testing.T) {
+	e, err := NewEnforcer(testPolicies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcReq := func(sub, op string, roles ...string) Request {
+		return Request{Subject: sub, Roles: roles, Kind: transport.KindGRPC, Operation: op}
+	}
+	editor := auth.Claims{"tenant": "acme", "scope": []interface{}{"user:read", "doc:write"}}
+	tests := []struct {
+		name   string
+		req    Request
+		allow  bool
+		policy string
+	}{
+		{"role binding", grpcReq("alice", "/lori.admin.v1.Admin/ListUsers"), true, "admin-all"},
+		{"role claim", grpcReq("bob", "/lori.admin.v1.Admin/ListUsers", "admin"), true, "admin-all"},
+		{"wildcard member", grpcReq("ops-1", "/lori.admin.v1.Admin/ListUsers"), true, "admin-all"},
+		{"deny overrides", grpcReq("ops-1", "/lori.admin.v1.Admin/DropTable"), false, "no-drop"},
+		{"no role", grpcReq("bob", "/lori.admin.v1.Admin/ListUsers"), false, ""},
+		{"http method", Request{Subject: "bob", Method: "GET", Operation: "/v1/user/:id"}, true, "user-read"},
+		{"http wrong method", Request{Subject: "bob", Method: "DELETE", Operation: "/v1/user/:id"}, false, ""},
+		{"grpc no method", grpcReq("bob", "/v1/user/:id"), false, ""},
+		{"abac", Request{Subject: "carol", Roles: []string{"editor"}, Method: "PUT", Operation: "/v1/doc/:id", Claims: editor}, true, "tenant"},
+		{"abac mismatch", Request{Subject: "carol", Roles: []string{"editor"}, Method: "PUT", Operation: "/v1/doc/:id",
+			Claims: auth.Claims{"tenant": "other", "scope": "doc:write"}}, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := e.Decide(tt.req)
+			if d.Allowed != tt.allow || d.Policy != tt.policy {
+				t.Errorf("expect allow %v by %q, got %+v", tt.allow, tt.policy, d)
+			}
+		})
+	}
+
+	if _, err = NewEnforcer(&PolicySet{Policies: []Policy{{Subjects: []string{"*"}, Resources: []string{"*"}, Effect: "maybe"}}}); err == nil {
+		t.Error("expect invalid effect error")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"/pkg.Svc/*", "/pkg.Svc/Get", true},
+		{"/pkg.Svc/*", "/pkg.Other/Get", false},
+		{"/v1/*/items", "/v1/a/b/items", true},
+		{"*Get*", "/pkg.Svc/GetUser", true},
+		{"exact", "exactly", false},
+	}
+	for _, tt := range tests {
+		if got := match(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	write := func(content string, mod time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"policies":[{"id":"v1","subjects":["*"],"resources":["/a"],"effect":"allow"}]}`, time.Now().Add(-time.Hour))
+	e, err := NewFileEnforcer(path, WithReloadInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	req := Request{Subject: "bob", Operation: "/a"}
+	if d := e.Decide(req); !d.Allowed {
+		t.Fatalf("expect allowed, got %+v", d)
+	}
+
+	waitFor := func(cond func(Decision) bool) bool {
+		for i := 0; i < 200; i++ {
+			if cond(e.Decide(req)) {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+	write(`{"policies":[{"id":"v2","subjects":["*"],"resources":["/a"],"effect":"deny"}]}`, time.Now())
+	if !waitFor(func(d Decision) bool { return d.Policy == "v2" && !d.Allowed }) {
+		t.Fatal("expect policy reloaded")
+	}
+	// 文件格式错误时保留旧策略
+	write(`{"policies":`, time.Now().Add(time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if d := e.Decide(req); d.Policy != "v2" {
+		t.Errorf("expect old policy kept, got %+v", d)
+	}
+
+	if _, err = NewFileEnforcer(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expect error for missing file")
+	}
+}
+
+var claimsAuthenticator = auth.AuthenticatorFunc(func(ctx context.Context, header transport.Header) (auth.Claims, error) {
+	return auth.Claims{"sub": header.Get("X-User"), "roles": []interface{}{header.Get("X-Role")}}, nil
+})
+
+func TestGin(t *testing.T) {
+	var buf bytes.Buffer
+	e, err := NewEnforcer(testPolicies, WithAuditLogger(log.NewStdLogger(&buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := lhttp.NewServer(lhttp.WithMode(gin.TestMode))
+	s.Use(auth.Gin(claimsAuthenticator), Gin(e))
+	s.Handle(http.MethodGet, "/v1/user/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	s.Handle(http.MethodDelete, "/v1/user/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for method, code := range map[string]int{http.MethodGet: http.StatusOK, http.MethodDelete: http.StatusForbidden} {
+		req := httptest.NewRequest(method, "/v1/user/1", nil)
+		req.Header.Set("X-User", "bob")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Errorf("%s: expect %d, got %d %s", method, code, w.Code, w.Body)
+		}
+	}
+	if audit := buf.String(); !strings.Contains(audit, "authz denied") || !strings.Contains(audit, "operation=/v1/user/:id") ||
+		!strings.Contains(audit, "method=DELETE") || strings.Count(audit, "authz") != 1 {
+		t.Errorf("unexpected audit log %q", audit)
+	}
+}
+
+type fakeTransport struct {
+	operation string
+}
+
+func (t fakeTransport) Kind() transport.Kind            { return transport.KindGRPC }
+func (t fakeTransport) Endpoint() string                { return "" }
+func (t fakeTransport) Operation() string               { return t.operation }
+func (t fakeTransport) RequestHeader() transport.Header { return nil }
+func (t fakeTransport) ReplyHeader() transport.Header   { return nil }
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	e, err := NewEnforcer(testPolicies, WithAuditLogger(log.NewStdLogger(&bytes.Buffer{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := UnaryServerInterceptor(e)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	call := func(sub, op string) error {
+		ctx := transport.NewServerContext(context.Background(), fakeTransport{operation: op})
+		ctx = auth.NewContext(ctx, auth.Claims{"sub": sub})
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: op}, handler)
+		return err
+	}
+	if err = call("alice", "/lori.admin.v1.Admin/DropTable"); err != nil {
+		t.Errorf("expect admin allowed, got %v", err)
+	}
+	if err = call("ops-1", "/lori.admin.v1.Admin/DropTable"); !errors.IsCode(err, ErrPermissionDenied) {
+		t.Errorf("expect permission denied, got %v", err)
+	}
+}
+
+func TestAnonymous(t *testing.T) {
+	e, err := NewEnforcer(&PolicySet{
+		Roles: map[string][]string{"viewer": {"*"}},
+		Policies: []Policy{
+			{ID: "any-user", Subjects: []string{"*"}, Resources: []string{"/lori.user.v1.User/*"}, Effect: EffectAllow},
+			{ID: "viewer", Subjects: []string{"role:viewer"}, Resources: []string{"/lori.doc.v1.Doc/*"}, Effect: EffectAllow},
+			{ID: "public", Subjects: []string{AnonymousSubject}, Resources: []string{"/lori.health.v1.Health/*"}, Effect: EffectAllow},
+		},
+	}, WithAuditLogger(log.NewStdLogger(&bytes.Buffer{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := UnaryServerInterceptor(e)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	call := func(claims auth.Claims, op string) error {
+		ctx := transport.NewServerContext(context.Background(), fakeTransport{operation: op})
+		if claims != nil {
+			ctx = auth.NewContext(ctx, claims)
+		}
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: op}, handler)
+		return err
+	}
+	for _, op := range []string{"/lori.user.v1.User/Get", "/lori.doc.v1.Doc/Get"} {
+		if err = call(nil, op); !errors.IsCode(err, ErrPermissionDenied) {
+			t.Errorf("%s: expect anonymous denied, got %v", op, err)
+		}
+		if err = call(auth.Claims{"sub": "bob"}, op); err != nil {
+			t.Errorf("%s: expect authenticated allowed, got %v", op, err)
+		}
+	}
+	if err = call(nil, "/lori.health.v1.Health/Check"); err != nil {
+		t.Errorf("expect anonymous allowed by explicit policy, got %v", err)
+	}
+	if err = call(auth.Claims{"sub": AnonymousSubject}, "/lori.health.v1.Health/Check"); !errors.IsCode(err, ErrPermissionDenied) {
+		t.Errorf("expect subject %q not treated as anonymous, got %v", AnonymousSubject, err)
+	}
+
+	if _, err = NewEnforcer(nil); err == nil {
+		t.Error("expect error for nil policy set")
+	}
+	if err = e.Load(nil); err == nil {
+		t.Error("expect error for nil policy set")
+	}
+}
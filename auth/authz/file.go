@@ -0,0 +1,97 @@
+package authz
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cr-mao/lori/log"
+)
+
+// WithReloadInterval 策略文件的检查间隔, 默认 5 秒, 小于等于 0 时不热加载
+func WithReloadInterval(interval time.Duration) Option {
+	return func(e *Enforcer) {
+		e.watcher = &watcher{interval: interval}
+	}
+}
+
+// watcher 定时检查策略文件的修改时间和大小, 变化时重新加载; 加载失败时保留旧策略
+type watcher struct {
+	path     string
+	interval time.Duration
+	modTime  time.Time
+	size     int64
+
+	once sync.Once
+	done chan struct{}
+}
+
+// NewFileEnforcer 从 json 策略文件加载, 文件变化时自动重新加载, 不再使用时调用 Close
+func NewFileEnforcer(path string, opts ...Option) (*Enforcer, error) {
+	e := newEnforcer(append([]Option{WithReloadInterval(5 * time.Second)}, opts...))
+	w := e.watcher
+	w.path = path
+	w.done = make(chan struct{})
+	if _, err := e.reload(); err != nil {
+		return nil, err
+	}
+	if w.interval > 0 {
+		go e.watch()
+	}
+	return e, nil
+}
+
+// Close 停止热加载
+func (e *Enforcer) Close() error {
+	if w := e.watcher; w != nil && w.done != nil {
+		w.once.Do(func() { close(w.done) })
+	}
+	return nil
+}
+
+func (e *Enforcer) watch() {
+	w := e.watcher
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			changed, err := e.reload()
+			if err != nil {
+				log.Errorf("[authz] reload policy file %s error: %s", w.path, err.Error())
+				continue
+			}
+			if changed {
+				log.Infof("[authz] policy file %s reloaded", w.path)
+			}
+		}
+	}
+}
+
+// reload 文件没有变化时不重新解析
+func (e *Enforcer) reload() (bool, error) {
+	w := e.watcher
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		return false, err
+	}
+	if fi.ModTime().Equal(w.modTime) && fi.Size() == w.size {
+		return false, nil
+	}
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return false, err
+	}
+	var set PolicySet
+	if err = json.Unmarshal(data, &set); err != nil {
+		return false, err
+	}
+	if err = e.Load(&set); err != nil {
+		return false, err
+	}
+	w.modTime, w.size = fi.ModTime(), fi.Size()
+	return true, nil
+}
@@ -0,0 +1,98 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+
+	"github.com/cr-mao/lori/auth"
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/middleware"
+	"github.com/cr-mao/lori/transport"
+	lhttp "github.com/cr-mao/lori/transport/http"
+	mids "github.com/cr-mao/lori/transport/http/middlewares"
+)
+
+// NewRequest 从 context 中的 transport 和 auth.Claims 构造授权请求,
+// 角色取 Claims 中的 roles (数组) 和 role (字符串); 没有 Claims 时为匿名请求
+func NewRequest(ctx context.Context) (Request, bool) {
+	tr, ok := transport.FromServerContext(ctx)
+	if !ok {
+		return Request{}, false
+	}
+	r := Request{Kind: tr.Kind(), Operation: tr.Operation()}
+	if ht, ok := tr.(lhttp.Transporter); ok {
+		r.Method = ht.Request().Method
+	}
+	claims, ok := auth.FromContext(ctx)
+	if !ok {
+		r.Anonymous = true
+		return r, true
+	}
+	r.Claims = claims
+	r.Subject = claims.Subject()
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		for _, role := range roles {
+			if s, ok := role.(string); ok {
+				r.Roles = append(r.Roles, s)
+			}
+		}
+	}
+	if role, ok := claims["role"].(string); ok {
+		r.Roles = append(r.Roles, role)
+	}
+	return r, true
+}
+
+func (e *Enforcer) enforce(ctx context.Context) error {
+	r, ok := NewRequest(ctx)
+	if !ok {
+		return errors.WithCode(ErrPermissionDenied, "missing transport")
+	}
+	return e.Enforce(ctx, r)
+}
+
+// Server 与传输层无关的授权中间件, 需要安装在 auth.Server 之后
+func Server(e *Enforcer) middleware.Middleware {
+	return func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if err := e.enforce(ctx); err != nil {
+				return nil, err
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// UnaryServerInterceptor grpc 授权拦截器, 需要安装在 auth.UnaryServerInterceptor 之后
+func UnaryServerInterceptor(e *Enforcer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := e.enforce(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor grpc 流式授权拦截器, 在建立流时授权
+func StreamServerInterceptor(e *Enforcer) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := e.enforce(ss.Context()); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// Gin gin 授权中间件, 需要安装在 auth.Gin 之后, 拒绝时直接按统一格式返回 403
+func Gin(e *Enforcer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := e.enforce(c.Request.Context()); err != nil {
+			mids.Render(c, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc"
+	grpcmd "google.golang.org/grpc/metadata"
+)
+
+// Credentials 客户端凭证, 返回要附加到请求上的请求头
+type Credentials interface {
+	Header(ctx context.Context) (key, value string, err error)
+}
+
+// CredentialsFunc 函数形式的 Credentials
+type CredentialsFunc func(ctx context.Context) (key, value string, err error)
+
+func (f CredentialsFunc) Header(ctx context.Context) (string, string, error) {
+	return f(ctx)
+}
+
+// Bearer 固定的 token, 写入 Authorization: Bearer <token>
+func Bearer(token string) Credentials {
+	return BearerFunc(func(context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// BearerFunc 每次请求时获取 token, 比如定时刷新的 token
+func BearerFunc(token func(ctx context.Context) (string, error)) Credentials {
+	return CredentialsFunc(func(ctx context.Context) (string, string, error) {
+		t, err := token(ctx)
+		if err != nil {
+			return "", "", err
+		}
+		return AuthorizationHeader, BearerPrefix + t, nil
+	})
+}
+
+// APIKey 写入 X-API-Key: <key>
+func APIKey(key string) Credentials {
+	return CredentialsFunc(func(context.Context) (string, string, error) {
+		return APIKeyHeader, key, nil
+	})
+}
+
+// UnaryClientInterceptor grpc 客户端拦截器, 把凭证写入 metadata, 通过 grpc.WithClientUnaryInterceptor 安装
+func UnaryClientInterceptor(creds Credentials) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, err := appendCredentials(ctx, creds)
+		if err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor grpc 流式客户端拦截器
+func StreamClientInterceptor(creds Credentials) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, err := appendCredentials(ctx, creds)
+		if err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+func appendCredentials(ctx context.Context, creds Credentials) (context.Context, error) {
+	key, value, err := creds.Header(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	return grpcmd.AppendToOutgoingContext(ctx, key, value), nil
+}
+
+// RoundTripper http 客户端把凭证写入请求头, base 为空时使用 http.DefaultTransport,
+// 和 lori http 客户端一起使用: http.WithClientTransport(auth.RoundTripper(nil, creds))
+func RoundTripper(base http.RoundTripper, creds Credentials) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		key, value, err := creds.Header(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		// RoundTripper 不能修改原请求
+		req = req.Clone(req.Context())
+		req.Header.Set(key, value)
+		return base.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
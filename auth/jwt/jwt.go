@@ -0,0 +1,376 @@
+// Package jwt JWT 认证, 支持 HS256/384/512, RS256/384/512, ES256/384/512,
+// 公钥可以从 JWKS 文件加载, 只依赖标准库
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cr-mao/lori/auth"
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/transport"
+)
+
+var _ auth.Authenticator = (*Authenticator)(nil)
+
+type key struct {
+	kid string
+	alg string // JWKS 中指定时 token 的 alg 必须一致
+	key interface{}
+}
+
+type algorithm struct {
+	hash crypto.Hash
+	kind string // HS, RS, ES
+}
+
+var algorithms = map[string]algorithm{
+	"HS256": {crypto.SHA256, "HS"},
+	"HS384": {crypto.SHA384, "HS"},
+	"HS512": {crypto.SHA512, "HS"},
+	"RS256": {crypto.SHA256, "RS"},
+	"RS384": {crypto.SHA384, "RS"},
+	"RS512": {crypto.SHA512, "RS"},
+	"ES256": {crypto.SHA256, "ES"},
+	"ES384": {crypto.SHA384, "ES"},
+	"ES512": {crypto.SHA512, "ES"},
+}
+
+// Option JWT 认证选项
+type Option func(*Authenticator)
+
+// WithHMACKey HS256/384/512 的密钥, token 带 kid 时只使用 kid 相同的 key, 否则尝试所有 key
+func WithHMACKey(kid string, secret []byte) Option {
+	return func(a *Authenticator) {
+		a.keys = append(a.keys, &key{kid: kid, key: secret})
+	}
+}
+
+// WithPublicKey RS 使用 *rsa.PublicKey, ES 使用 *ecdsa.PublicKey
+func WithPublicKey(kid string, pub crypto.PublicKey) Option {
+	return func(a *Authenticator) {
+		a.keys = append(a.keys, &key{kid: kid, key: pub})
+	}
+}
+
+// WithJWKS 从 JWKS json 加载公钥
+func WithJWKS(data []byte) Option {
+	return func(a *Authenticator) {
+		keys, err := parseJWKS(data)
+		if err != nil {
+			a.err = err
+			return
+		}
+		a.keys = append(a.keys, keys...)
+	}
+}
+
+// WithJWKSFile 从 JWKS 文件加载公钥
+func WithJWKSFile(path string) Option {
+	return func(a *Authenticator) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			a.err = err
+			return
+		}
+		WithJWKS(data)(a)
+	}
+}
+
+// WithIssuer 校验 iss
+func WithIssuer(issuer string) Option {
+	return func(a *Authenticator) {
+		a.issuer = issuer
+	}
+}
+
+// WithAudience 校验 aud 中包含 audience
+func WithAudience(audience string) Option {
+	return func(a *Authenticator) {
+		a.audience = audience
+	}
+}
+
+// WithLeeway 校验 exp, nbf 时允许的时钟误差, 默认 1 分钟
+func WithLeeway(leeway time.Duration) Option {
+	return func(a *Authenticator) {
+		a.leeway = leeway
+	}
+}
+
+// WithRequireExpiration 是否要求 token 带有 exp, 默认要求, 没有 exp 的 token 永不过期
+func WithRequireExpiration(require bool) Option {
+	return func(a *Authenticator) {
+		a.requireExp = require
+	}
+}
+
+// Authenticator 从 Authorization: Bearer <token> 读取 JWT, 认证通过后 Claims 为 token 的 payload
+type Authenticator struct {
+	keys       []*key
+	issuer     string
+	audience   string
+	leeway     time.Duration
+	requireExp bool
+	now        func() time.Time
+	err        error
+}
+
+// New 至少需要一个 key, JWKS 加载失败时返回错误
+func New(opts ...Option) (*Authenticator, error) {
+	a := &Authenticator{leeway: time.Minute, requireExp: true, now: time.Now}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.err != nil {
+		return nil, a.err
+	}
+	if len(a.keys) == 0 {
+		return nil, fmt.Errorf("jwt: no verification key")
+	}
+	return a, nil
+}
+
+// Authenticate 没有 Bearer token 时返回 auth.ErrMissingCredentials
+func (a *Authenticator) Authenticate(_ context.Context, header transport.Header) (auth.Claims, error) {
+	v := header.Get(auth.AuthorizationHeader)
+	if len(v) <= len(auth.BearerPrefix) || !strings.EqualFold(v[:len(auth.BearerPrefix)], auth.BearerPrefix) {
+		return nil, errors.WrapC(auth.ErrMissingCredentials, auth.ErrUnauthenticated, "missing bearer token")
+	}
+	return a.Parse(v[len(auth.BearerPrefix):])
+}
+
+// Parse 校验签名和 exp, nbf, iss, aud, 返回 payload; 默认要求 token 带有 exp
+func (a *Authenticator) Parse(token string) (auth.Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.WithCode(auth.ErrUnauthenticated, "malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.WrapC(err, auth.ErrUnauthenticated, "malformed token header")
+	}
+	alg, ok := algorithms[header.Alg]
+	if !ok {
+		return nil, errors.WithCode(auth.ErrUnauthenticated, "unsupported alg %q", header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.WrapC(err, auth.ErrUnauthenticated, "malformed token signature")
+	}
+	if !a.verify(header.Alg, alg, header.Kid, []byte(parts[0]+"."+parts[1]), sig) {
+		return nil, errors.WithCode(auth.ErrUnauthenticated, "invalid token signature")
+	}
+	var claims auth.Claims
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.WrapC(err, auth.ErrUnauthenticated, "malformed token payload")
+	}
+	if err = a.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// verify 只用类型和 alg 匹配的 key 校验, 防止用 RSA 公钥作为 HMAC 密钥伪造 token
+func (a *Authenticator) verify(name string, alg algorithm, kid string, input, sig []byte) bool {
+	for _, k := range a.keys {
+		if (kid != "" && k.kid != kid) || (k.alg != "" && k.alg != name) {
+			continue
+		}
+		if verify(alg, k.key, input, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+func verify(alg algorithm, k interface{}, input, sig []byte) bool {
+	switch alg.kind {
+	case "HS":
+		secret, ok := k.([]byte)
+		if !ok {
+			return false
+		}
+		mac := hmac.New(alg.hash.New, secret)
+		mac.Write(input)
+		return hmac.Equal(sig, mac.Sum(nil))
+	case "RS":
+		pub, ok := k.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		return rsa.VerifyPKCS1v15(pub, alg.hash, digest(alg.hash, input), sig) == nil
+	case "ES":
+		pub, ok := k.(*ecdsa.PublicKey)
+		if !ok || pub.Curve.Params().BitSize != esBits(alg.hash) {
+			return false
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		return ecdsa.Verify(pub, digest(alg.hash, input), r, s)
+	}
+	return false
+}
+
+// esBits ES256 使用 P-256, ES384 使用 P-384, ES512 使用 P-521
+func esBits(h crypto.Hash) int {
+	switch h {
+	case crypto.SHA256:
+		return 256
+	case crypto.SHA384:
+		return 384
+	}
+	return 521
+}
+
+// validate exp, nbf 存在时必须是数字, 否则拒绝, 不能因为类型不对跳过校验
+func (a *Authenticator) validate(claims auth.Claims) error {
+	now := a.now()
+	exp, ok, err := numericDate(claims, "exp")
+	if err != nil {
+		return err
+	}
+	if !ok && a.requireExp {
+		return errors.WithCode(auth.ErrUnauthenticated, "token has no exp")
+	}
+	if ok && now.After(exp.Add(a.leeway)) {
+		return errors.WithCode(auth.ErrUnauthenticated, "token expired")
+	}
+	nbf, ok, err := numericDate(claims, "nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(a.leeway).Before(nbf) {
+		return errors.WithCode(auth.ErrUnauthenticated, "token not valid yet")
+	}
+	if a.issuer != "" && claims["iss"] != a.issuer {
+		return errors.WithCode(auth.ErrUnauthenticated, "invalid token issuer")
+	}
+	if a.audience != "" && !hasAudience(claims["aud"], a.audience) {
+		return errors.WithCode(auth.ErrUnauthenticated, "invalid token audience")
+	}
+	return nil
+}
+
+// numericDate 读取 exp, nbf 这样的时间戳(秒), 不存在时 ok 为 false, 不是数字时返回错误
+func numericDate(claims auth.Claims, name string) (time.Time, bool, error) {
+	v, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	sec, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false, errors.WithCode(auth.ErrUnauthenticated, "invalid token %s %v", name, v)
+	}
+	return time.Unix(int64(sec), 0), true, nil
+}
+
+// hasAudience aud 可以是字符串或者字符串数组
+func hasAudience(aud interface{}, audience string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == audience
+	case []interface{}:
+		for _, a := range v {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Sign 签发 token, HS 使用 []byte 密钥, RS 使用 *rsa.PrivateKey, ES 使用 *ecdsa.PrivateKey; kid 为空时不写入 header
+func Sign(alg string, claims auth.Claims, k interface{}, kid string) (string, error) {
+	a, ok := algorithms[alg]
+	if !ok {
+		return "", fmt.Errorf("jwt: unsupported alg %q", alg)
+	}
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	h, err := encodeSegment(header)
+	if err != nil {
+		return "", err
+	}
+	p, err := encodeSegment(claims)
+	if err != nil {
+		return "", err
+	}
+	input := h + "." + p
+	var sig []byte
+	switch key := k.(type) {
+	case []byte:
+		if a.kind != "HS" {
+			return "", fmt.Errorf("jwt: %s requires a hmac secret", alg)
+		}
+		mac := hmac.New(a.hash.New, key)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		if a.kind != "RS" {
+			return "", fmt.Errorf("jwt: %s requires a rsa key", alg)
+		}
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, key, a.hash, digest(a.hash, []byte(input))); err != nil {
+			return "", err
+		}
+	case *ecdsa.PrivateKey:
+		if a.kind != "ES" || key.Curve.Params().BitSize != esBits(a.hash) {
+			return "", fmt.Errorf("jwt: %s requires a matching ecdsa key", alg)
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest(a.hash, []byte(input)))
+		if err != nil {
+			return "", err
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		sig = make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+	default:
+		return "", fmt.Errorf("jwt: unsupported key type %T", k)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func digest(h crypto.Hash, data []byte) []byte {
+	hh := h.New()
+	hh.Write(data)
+	return hh.Sum(nil)
+}
+
+func encodeSegment(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,147 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cr-mao/lori/auth"
+	"github.com/cr-mao/lori/errors"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func TestSignAndParse(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ec384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	secret := []byte("secret")
+
+	// JWKS 文件, 和 WithHMACKey 一起使用
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "alg": "RS256", "use": "sig",
+			"n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec256", "crv": "P-256",
+			"x": b64(ec256.X.FillBytes(make([]byte, 32))), "y": b64(ec256.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "EC", "kid": "ec384", "crv": "P-384",
+			"x": b64(ec384.X.FillBytes(make([]byte, 48))), "y": b64(ec384.Y.FillBytes(make([]byte, 48)))},
+		{"kty": "RSA", "kid": "enc", "use": "enc"},
+	}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err = os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := New(WithJWKSFile(path), WithHMACKey("", secret), WithIssuer("lori"), WithAudience("api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Unix()
+	valid := auth.Claims{"sub": "user-1", "iss": "lori", "aud": []string{"api", "web"}, "exp": now + 60}
+	tests := []struct {
+		name   string
+		alg    string
+		claims auth.Claims
+		key    interface{}
+		kid    string
+		err    string
+	}{
+		{"HS256", "HS256", valid, secret, "", ""},
+		{"HS512", "HS512", valid, secret, "", ""},
+		{"RS256", "RS256", valid, rsaKey, "rsa", ""},
+		{"ES256", "ES256", valid, ec256, "ec256", ""},
+		{"ES384", "ES384", valid, ec384, "ec384", ""},
+		{"jwks alg mismatch", "RS512", valid, rsaKey, "rsa", "invalid token signature"},
+		{"unknown kid", "ES256", valid, ec256, "nope", "invalid token signature"},
+		{"wrong secret", "HS256", valid, []byte("other"), "", "invalid token signature"},
+		{"expired", "HS256", auth.Claims{"iss": "lori", "aud": "api", "exp": now - 120}, secret, "", "token expired"},
+		{"not before", "HS256", auth.Claims{"iss": "lori", "aud": "api", "exp": now + 60, "nbf": now + 120}, secret, "", "token not valid yet"},
+		{"issuer", "HS256", auth.Claims{"iss": "other", "aud": "api", "exp": now + 60}, secret, "", "invalid token issuer"},
+		{"audience", "HS256", auth.Claims{"iss": "lori", "aud": "web", "exp": now + 60}, secret, "", "invalid token audience"},
+		{"no exp", "HS256", auth.Claims{"sub": "user-1", "iss": "lori", "aud": "api"}, secret, "", "token has no exp"},
+		{"string exp", "HS256", auth.Claims{"iss": "lori", "aud": "api", "exp": "9999999999"}, secret, "", "invalid token exp"},
+		{"string nbf", "HS256", auth.Claims{"iss": "lori", "aud": "api", "exp": now + 60, "nbf": "0"}, secret, "", "invalid token nbf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := Sign(tt.alg, tt.claims, tt.key, tt.kid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims, err := a.Parse(token)
+			if tt.err == "" {
+				if err != nil || claims.Subject() != "user-1" {
+					t.Fatalf("unexpected claims %v %v", claims, err)
+				}
+				return
+			}
+			if !errors.IsCode(err, auth.ErrUnauthenticated) || !strings.Contains(fmt.Sprintf("%-v", err), tt.err) {
+				t.Fatalf("expect error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestRequireExpiration(t *testing.T) {
+	secret := []byte("secret")
+	a, err := New(WithHMACKey("", secret), WithRequireExpiration(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := Sign("HS256", auth.Claims{"sub": "user-1"}, secret, "")
+	if claims, err := a.Parse(token); err != nil || claims.Subject() != "user-1" {
+		t.Errorf("expect token without exp accepted, got %v %v", claims, err)
+	}
+	// 不要求 exp 时, 类型不对的 exp 仍然拒绝
+	token, _ = Sign("HS256", auth.Claims{"sub": "user-1", "exp": "never"}, secret, "")
+	if _, err = a.Parse(token); !errors.IsCode(err, auth.ErrUnauthenticated) {
+		t.Errorf("expect unauthenticated, got %v", err)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := New(WithPublicKey("", &rsaKey.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := Sign("RS256", auth.Claims{"sub": "user-1", "exp": time.Now().Unix() + 60}, rsaKey, "")
+	parts := strings.Split(token, ".")
+
+	// 用公钥作为 HMAC 密钥伪造的 token
+	pubDER, _ := json.Marshal(rsaKey.PublicKey)
+	forged, _ := Sign("HS256", auth.Claims{"sub": "admin"}, pubDER, "")
+	// 篡改 payload
+	tampered := parts[0] + "." + b64([]byte(`{"sub":"admin"}`)) + "." + parts[2]
+	none := b64([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."
+
+	for name, token := range map[string]string{"forged": forged, "tampered": tampered, "none": none, "malformed": "abc"} {
+		if _, err := a.Parse(token); !errors.IsCode(err, auth.ErrUnauthenticated) {
+			t.Errorf("%s: expect unauthenticated, got %v", name, err)
+		}
+	}
+	if _, err = New(); err == nil {
+		t.Error("expect error without keys")
+	}
+	if _, err = New(WithJWKSFile(filepath.Join(t.TempDir(), "missing.json"))); err == nil {
+		t.Error("expect error for missing jwks file")
+	}
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/middleware"
+	"github.com/cr-mao/lori/transport"
+	mids "github.com/cr-mao/lori/transport/http/middlewares"
+)
+
+// Option 服务端认证选项
+type Option func(*options)
+
+type options struct {
+	allowList []string
+}
+
+// WithAllowList 不需要认证的 operation, grpc 为 /package.Service/Method, http 为路由模板 /v1/user/:id;
+// 以 * 结尾时按前缀匹配, 比如 /grpc.health.v1.Health/*
+func WithAllowList(operations ...string) Option {
+	return func(o *options) {
+		o.allowList = append(o.allowList, operations...)
+	}
+}
+
+func (o *options) allowed(operation string) bool {
+	for _, op := range o.allowList {
+		if prefix := strings.TrimSuffix(op, "*"); prefix != op {
+			if strings.HasPrefix(operation, prefix) {
+				return true
+			}
+		} else if op == operation {
+			return true
+		}
+	}
+	return false
+}
+
+func newAuthenticate(a Authenticator, opts []Option) func(ctx context.Context) (context.Context, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(ctx context.Context) (context.Context, error) {
+		tr, ok := transport.FromServerContext(ctx)
+		if !ok {
+			return ctx, errors.WithCode(ErrUnauthenticated, "missing transport")
+		}
+		if o.allowed(tr.Operation()) {
+			return ctx, nil
+		}
+		claims, err := a.Authenticate(ctx, tr.RequestHeader())
+		if err != nil {
+			return ctx, err
+		}
+		return NewContext(ctx, claims), nil
+	}
+}
+
+// Server 与传输层无关的认证中间件, 通过 http/grpc 的 WithMiddleware 安装
+func Server(a Authenticator, opts ...Option) middleware.Middleware {
+	authenticate := newAuthenticate(a, opts)
+	return func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			ctx, err := authenticate(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// UnaryServerInterceptor grpc 认证拦截器, 通过 grpc.WithUnaryInterceptor 安装
+func UnaryServerInterceptor(a Authenticator, opts ...Option) grpc.UnaryServerInterceptor {
+	authenticate := newAuthenticate(a, opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor grpc 流式认证拦截器, 在建立流时认证
+func StreamServerInterceptor(a Authenticator, opts ...Option) grpc.StreamServerInterceptor {
+	authenticate := newAuthenticate(a, opts)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// Gin gin 认证中间件, 认证失败时直接按统一格式返回, 不依赖安装顺序
+func Gin(a Authenticator, opts ...Option) gin.HandlerFunc {
+	authenticate := newAuthenticate(a, opts)
+	return func(c *gin.Context) {
+		ctx, err := authenticate(c.Request.Context())
+		if err != nil {
+			mids.Render(c, err)
+			c.Abort()
+			return
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
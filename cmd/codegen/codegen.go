@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 注释格式: ErrUserNotFound - 404: User not found.
+var commentRe = regexp.MustCompile(`^(\w+)\s+-\s+(\d{3}):\s*(.*)$`)
+
+// 注释格式: Reference: https://...
+var referenceRe = regexp.MustCompile(`^(?i:reference|ref):\s*(\S+)$`)
+
+// Code 一个错误码常量
+type Code struct {
+	Name      string `json:"name"`
+	Code      int    `json:"code"`
+	HTTP      int    `json:"http"`
+	Message   string `json:"message"`
+	Reference string `json:"reference,omitempty"`
+	pos       token.Position
+}
+
+// Package 扫描结果
+type Package struct {
+	Name  string
+	Codes []*Code
+}
+
+// Generator 错误码代码和文档生成
+type Generator struct {
+	TypeName  string
+	Allowed   map[int]bool // 允许的 http 状态码, 为空不检查
+	Reference string       // 默认文档地址
+	Command   string       // 写进生成文件头部的命令
+}
+
+// Parse 扫描 dir 下 TypeName 类型, 且有错误码注释的常量, 忽略测试文件和 skip 文件
+func (g *Generator) Parse(dir string, skip string) (*Package, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != skip
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expect one package in %s, found %d", dir, len(pkgs))
+	}
+	var astPkg *ast.Package
+	for _, p := range pkgs {
+		astPkg = p
+	}
+	files := make([]*ast.File, 0, len(astPkg.Files))
+	names := make([]string, 0, len(astPkg.Files))
+	for name := range astPkg.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		files = append(files, astPkg.Files[name])
+	}
+
+	// 只需要常量的值, 依赖包导入失败等类型错误忽略
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	conf := types.Config{Importer: importer.Default(), Error: func(error) {}}
+	tpkg, _ := conf.Check(astPkg.Name, fset, files, info)
+
+	pkg := &Package{Name: astPkg.Name}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				vs := spec.(*ast.ValueSpec)
+				// const X int = 1 的注释在 GenDecl 上
+				if vs.Doc == nil && !gd.Lparen.IsValid() {
+					vs.Doc = gd.Doc
+				}
+				for _, name := range vs.Names {
+					code, err := g.parseConst(fset, tpkg, info, vs, name)
+					if err != nil {
+						return nil, err
+					}
+					if code != nil {
+						pkg.Codes = append(pkg.Codes, code)
+					}
+				}
+			}
+		}
+	}
+	sort.SliceStable(pkg.Codes, func(i, j int) bool { return pkg.Codes[i].Code < pkg.Codes[j].Code })
+	return pkg, g.check(pkg)
+}
+
+func (g *Generator) parseConst(fset *token.FileSet, tpkg *types.Package, info *types.Info,
+	vs *ast.ValueSpec, name *ast.Ident) (*Code, error) {
+	obj, ok := info.Defs[name].(*types.Const)
+	if !ok || types.TypeString(obj.Type(), types.RelativeTo(tpkg)) != g.TypeName {
+		return nil, nil
+	}
+	doc := vs.Doc
+	if doc == nil {
+		doc = vs.Comment
+	}
+	if doc == nil {
+		return nil, nil
+	}
+
+	var code *Code
+	pos := fset.Position(name.Pos())
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		line = strings.TrimSpace(line)
+		if m := commentRe.FindStringSubmatch(line); m != nil && m[1] == name.Name {
+			httpStatus, _ := strconv.Atoi(m[2])
+			code = &Code{Name: name.Name, HTTP: httpStatus, Message: m[3], pos: pos}
+			continue
+		}
+		if m := referenceRe.FindStringSubmatch(line); m != nil && code != nil {
+			code.Reference = m[1]
+		}
+	}
+	if code == nil {
+		return nil, nil
+	}
+	v, ok := constant.Int64Val(obj.Val())
+	if !ok {
+		return nil, fmt.Errorf("%s: %s is not an integer constant", pos, name.Name)
+	}
+	code.Code = int(v)
+	if code.Reference == "" && g.Reference != "" {
+		code.Reference = g.Reference + "#" + strconv.Itoa(code.Code)
+	}
+	return code, nil
+}
+
+// check 错误码重复, 保留错误码, http 状态码不在允许范围
+func (g *Generator) check(pkg *Package) error {
+	var errs []string
+	seen := make(map[int]*Code, len(pkg.Codes))
+	for _, c := range pkg.Codes {
+		if c.Code == 0 {
+			errs = append(errs, fmt.Sprintf("%s: %s uses reserved code 0", c.pos, c.Name))
+		}
+		if prev, ok := seen[c.Code]; ok {
+			errs = append(errs, fmt.Sprintf("%s: duplicate code %d: %s and %s", c.pos, c.Code, prev.Name, c.Name))
+		}
+		seen[c.Code] = c
+		if len(g.Allowed) > 0 && !g.Allowed[c.HTTP] {
+			errs = append(errs, fmt.Sprintf("%s: %s uses http status %d which is not allowed", c.pos, c.Name, c.HTTP))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// Source 生成注册代码
+func (g *Generator) Source(pkg *Package) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by \"%s\"; DO NOT EDIT.\n\n", g.Command)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg.Name)
+	fmt.Fprintf(&buf, "import \"github.com/cr-mao/lori/errors\"\n\n")
+	fmt.Fprintf(&buf, "func init() {\n")
+	for _, c := range pkg.Codes {
+		fmt.Fprintf(&buf, "\terrors.MustRegister(errors.NewCoder(%s, %d, %q, %q))\n", c.Name, c.HTTP, c.Message, c.Reference)
+	}
+	fmt.Fprintf(&buf, "}\n")
+	return format.Source(buf.Bytes())
+}
+
+// Markdown 生成 markdown 错误码文档
+func (g *Generator) Markdown(pkg *Package) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# 错误码\n\n")
+	fmt.Fprintf(&buf, "！！系统错误码列表, 由 `%s` 命令生成, 不要对此文件做任何更改.\n\n", g.Command)
+	fmt.Fprintf(&buf, "| Identifier | Code | HTTP Code | Description | Reference |\n")
+	fmt.Fprintf(&buf, "| ---------- | ---- | --------- | ----------- | --------- |\n")
+	for _, c := range pkg.Codes {
+		fmt.Fprintf(&buf, "| %s | %d | %d | %s | %s |\n", c.Name, c.Code, c.HTTP,
+			strings.ReplaceAll(c.Message, "|", "\\|"), c.Reference)
+	}
+	return buf.Bytes()
+}
+
+// JSON 生成 json 错误码文档
+func (g *Generator) JSON(pkg *Package) ([]byte, error) {
+	return json.MarshalIndent(pkg.Codes, "", "  ")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newGenerator() *Generator {
+	allowed, _ := parseAllowed("200,400,401,403,404,500")
+	return &Generator{TypeName: "int", Allowed: allowed, Reference: "https://example.com/codes", Command: "codegen -type=int"}
+}
+
+func TestGenerate(t *testing.T) {
+	g := newGenerator()
+	pkg, err := g.Parse("testdata/ok", "code_generated.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkg.Codes) != 3 {
+		t.Fatalf("expect 3 codes, got %d", len(pkg.Codes))
+	}
+
+	src, err := g.Source(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`errors.MustRegister(errors.NewCoder(ErrDatabase, 500, "Database error.", "https://example.com/codes#100101"))`,
+		`errors.MustRegister(errors.NewCoder(ErrUserNotFound, 404, "User not found.", "https://example.com/user"))`,
+		`errors.MustRegister(errors.NewCoder(ErrUserEncode, 400, "User encode error.", "https://example.com/codes#110002"))`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source missing %s:\n%s", want, src)
+		}
+	}
+
+	if md := string(g.Markdown(pkg)); !strings.Contains(md, "| ErrUserNotFound | 110001 | 404 | User not found. | https://example.com/user |") {
+		t.Errorf("unexpected markdown:\n%s", md)
+	}
+
+	data, err := g.JSON(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var codes []Code
+	if err = json.Unmarshal(data, &codes); err != nil {
+		t.Fatal(err)
+	}
+	if codes[0].Name != "ErrDatabase" || codes[0].Code != 100101 || codes[0].HTTP != 500 {
+		t.Errorf("unexpected json %s", data)
+	}
+}
+
+func TestGenerateCheck(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"testdata/dup", "duplicate code 100001: ErrA and ErrB"},
+		{"testdata/badhttp", "ErrTeapot uses http status 418 which is not allowed"},
+	}
+	for _, tt := range tests {
+		_, err := newGenerator().Parse(tt.dir, "code_generated.go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expect error %q, got %v", tt.dir, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// codegen 扫描带注释的错误码常量, 生成 errors.MustRegister 注册代码和错误码文档.
+//
+// 常量注释格式:
+//
+//	// ErrUserNotFound - 404: User not found.
+//	// Reference: https://github.com/cr-mao/lori/errors/README.md
+//	ErrUserNotFound int = iota + 110001
+//
+// 使用:
+//
+//	//go:generate codegen -type=int
+//	//go:generate codegen -type=int -doc=../../docs/error_code.md -json=../../docs/error_code.json
+//
+// 错误码重复, 或者 http 状态码不在允许的范围内时返回非0, go generate 失败.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	typeName    = flag.String("type", "int", "错误码常量的类型")
+	output      = flag.String("output", "", "注册代码输出文件, 默认 <dir>/code_generated.go")
+	docFile     = flag.String("doc", "", "markdown 文档输出文件, 为空不生成")
+	jsonFile    = flag.String("json", "", "json 文档输出文件, 为空不生成")
+	allowedHTTP = flag.String("http", "200,400,401,403,404,409,429,499,500,503,504", "允许使用的 http 状态码, 逗号分隔")
+	reference   = flag.String("reference", "", "没有 Reference 注释时使用的文档地址, 会拼接 #<code>")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage of codegen:\n")
+	fmt.Fprintf(os.Stderr, "\tcodegen [flags] -type T [directory]\n")
+	fmt.Fprintf(os.Stderr, "Flags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	dir := "."
+	if args := flag.Args(); len(args) > 0 {
+		dir = args[0]
+	}
+	allowed, err := parseAllowed(*allowedHTTP)
+	if err != nil {
+		fatal(err)
+	}
+	if *output == "" {
+		*output = filepath.Join(dir, "code_generated.go")
+	}
+
+	g := &Generator{
+		TypeName:  *typeName,
+		Allowed:   allowed,
+		Reference: *reference,
+		Command:   "codegen " + strings.Join(os.Args[1:], " "),
+	}
+	pkg, err := g.Parse(dir, filepath.Base(*output))
+	if err != nil {
+		fatal(err)
+	}
+	if len(pkg.Codes) == 0 {
+		fatal(fmt.Errorf("no annotated constants of type %s in %s", *typeName, dir))
+	}
+
+	src, err := g.Source(pkg)
+	if err != nil {
+		fatal(err)
+	}
+	if err = os.WriteFile(*output, src, 0644); err != nil {
+		fatal(err)
+	}
+	if *docFile != "" {
+		if err = os.WriteFile(*docFile, g.Markdown(pkg), 0644); err != nil {
+			fatal(err)
+		}
+	}
+	if *jsonFile != "" {
+		data, err := g.JSON(pkg)
+		if err != nil {
+			fatal(err)
+		}
+		if err = os.WriteFile(*jsonFile, data, 0644); err != nil {
+			fatal(err)
+		}
+	}
+}
+
+func parseAllowed(s string) (map[int]bool, error) {
+	allowed := make(map[int]bool)
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		code, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid http status %q", v)
+		}
+		allowed[code] = true
+	}
+	return allowed, nil
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "codegen: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,4 @@
+package code
+
+// ErrTeapot - 418: I'm a teapot.
+const ErrTeapot int = 100001
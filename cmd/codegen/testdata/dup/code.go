@@ -0,0 +1,9 @@
+package code
+
+const (
+	// ErrA - 400: A.
+	ErrA int = 100001
+
+	// ErrB - 400: B.
+	ErrB int = 100001
+)
@@ -0,0 +1,19 @@
+package code
+
+const (
+	// ErrUserNotFound - 404: User not found.
+	// Reference: https://example.com/user
+	ErrUserNotFound int = iota + 110001
+
+	// ErrUserEncode - 400: User encode error.
+	ErrUserEncode
+
+	// 没有注释的常量会被忽略
+	ErrIgnored
+)
+
+// ErrDatabase - 500: Database error.
+const ErrDatabase int = 100101
+
+// MaxSize 不是错误码
+const MaxSize int64 = 100
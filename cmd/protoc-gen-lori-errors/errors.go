@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cr-mao/lori/errors"
+)
+
+const errorsPackage = protogen.GoImportPath("github.com/cr-mao/lori/errors")
+
+// reason 一个需要生成的 enum 值
+type reason struct {
+	value   *protogen.EnumValue
+	name    string // UserNotFound
+	http    int
+	message string
+}
+
+// generateFile 生成 _errors.pb.go, 没有带 (lori.errors.default_code) 的 enum 时不生成文件
+func generateFile(gen *protogen.Plugin, file *protogen.File) *protogen.GeneratedFile {
+	var reasons []reason
+	for _, enum := range file.Enums {
+		reasons = append(reasons, enumReasons(enum)...)
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	g := gen.NewGeneratedFile(file.GeneratedFilenamePrefix+"_errors.pb.go", file.GoImportPath)
+	g.P("// Code generated by protoc-gen-lori-errors. DO NOT EDIT.")
+	g.P("// versions:")
+	g.P("// - protoc-gen-lori-errors ", version)
+	g.P("// source: ", file.Desc.Path())
+	g.P()
+	g.P("package ", file.GoPackageName)
+	g.P()
+
+	g.P("func init() {")
+	for _, r := range reasons {
+		g.P(errorsPackage.Ident("MustRegister"), "(", errorsPackage.Ident("NewReasonCoder"), "(int(", r.value.GoIdent, "), ",
+			r.http, ", ", strconv.Quote(string(r.value.Desc.Name())), ", ", strconv.Quote(r.message), `, ""))`)
+	}
+	g.P("}")
+	g.P()
+
+	for _, r := range reasons {
+		comment := r.message
+		if comment == "" {
+			comment = string(r.value.Desc.Name())
+		}
+		g.P("// Is", r.name, " ", comment)
+		g.P("func Is", r.name, "(err error) bool {")
+		g.P("return ", errorsPackage.Ident("IsCode"), "(err, int(", r.value.GoIdent, "))")
+		g.P("}")
+		g.P()
+		g.P("// Error", r.name, " ", comment)
+		g.P("func Error", r.name, "(format string, args ...interface{}) error {")
+		g.P("return ", errorsPackage.Ident("WithCode"), "(int(", r.value.GoIdent, "), format, args...)")
+		g.P("}")
+		g.P()
+	}
+	return g
+}
+
+// enumReasons 带有 (lori.errors.default_code) 选项的 enum, 值为 0 的跳过(lori 保留错误码)
+func enumReasons(enum *protogen.Enum) []reason {
+	defaultCode := proto.GetExtension(enum.Desc.Options(), errors.E_DefaultCode).(int32)
+	if defaultCode == 0 {
+		return nil
+	}
+	reasons := make([]reason, 0, len(enum.Values))
+	for _, v := range enum.Values {
+		if v.Desc.Number() == 0 {
+			continue
+		}
+		code := proto.GetExtension(v.Desc.Options(), errors.E_Code).(int32)
+		if code == 0 {
+			code = defaultCode
+		}
+		reasons = append(reasons, reason{
+			value:   v,
+			name:    camelCase(string(v.Desc.Name())),
+			http:    int(code),
+			message: leadingComment(v.Comments.Leading),
+		})
+	}
+	return reasons
+}
+
+// camelCase USER_NOT_FOUND -> UserNotFound
+func camelCase(s string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(strings.ToLower(s), "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	return b.String()
+}
+
+// leadingComment enum 值上方注释的第一行作为对外的错误信息
+func leadingComment(c protogen.Comments) string {
+	for _, line := range strings.Split(string(c), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
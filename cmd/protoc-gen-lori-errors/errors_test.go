@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/cr-mao/lori/errors"
+)
+
+func TestCamelCase(t *testing.T) {
+	tests := map[string]string{
+		"USER_NOT_FOUND":  "UserNotFound",
+		"user__not_found": "UserNotFound",
+		"TIMEOUT":         "Timeout",
+	}
+	for in, want := range tests {
+		if got := camelCase(in); got != want {
+			t.Errorf("camelCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// testFile 构造一个 proto 文件: ErrorReason 带 default_code, Status 没有
+func testFile(t *testing.T, withOption bool) *protogen.Plugin {
+	t.Helper()
+	enumOpts := &descriptorpb.EnumOptions{}
+	if withOption {
+		proto.SetExtension(enumOpts, errors.E_DefaultCode, int32(500))
+	}
+	notFoundOpts := &descriptorpb.EnumValueOptions{}
+	proto.SetExtension(notFoundOpts, errors.E_Code, int32(404))
+
+	fd := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("user/v1/user_error.proto"),
+		Package:    proto.String("user.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{errors.File_errors_errors_proto.Path()},
+		Options: &descriptorpb.FileOptions{
+			GoPackage: proto.String("example.com/user/v1;v1"),
+		},
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			{
+				Name:    proto.String("ErrorReason"),
+				Options: enumOpts,
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("ERROR_REASON_UNSPECIFIED"), Number: proto.Int32(0)},
+					{Name: proto.String("USER_NOT_FOUND"), Number: proto.Int32(10001), Options: notFoundOpts},
+					{Name: proto.String("DB_ERROR"), Number: proto.Int32(10002)},
+				},
+			},
+			{
+				Name: proto.String("Status"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("STATUS_UNSPECIFIED"), Number: proto.Int32(0)},
+					{Name: proto.String("ACTIVE"), Number: proto.Int32(1)},
+				},
+			},
+		},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+			Location: []*descriptorpb.SourceCodeInfo_Location{{
+				// enum_type[0].value[1]
+				Path:            []int32{5, 0, 2, 1},
+				Span:            []int32{0, 0, 0},
+				LeadingComments: proto.String(" user not found\n more details\n"),
+			}},
+		},
+	}
+	gen, err := protogen.Options{}.New(&pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{fd.GetName()},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+			protodesc.ToFileDescriptorProto(errors.File_errors_errors_proto),
+			fd,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gen
+}
+
+func TestGenerateFile(t *testing.T) {
+	gen := testFile(t, true)
+	if g := generateFile(gen, gen.Files[len(gen.Files)-1]); g == nil {
+		t.Fatal("expect generated file")
+	}
+	resp := gen.Response()
+	if resp.Error != nil {
+		t.Fatal(resp.GetError())
+	}
+	if len(resp.File) != 1 || resp.File[0].GetName() != "example.com/user/v1/user_error_errors.pb.go" {
+		t.Fatalf("unexpected files %v", resp.File)
+	}
+	content := resp.File[0].GetContent()
+	for _, want := range []string{
+		"package v1",
+		`errors "github.com/cr-mao/lori/errors"`,
+		`errors.MustRegister(errors.NewReasonCoder(int(ErrorReason_USER_NOT_FOUND), 404, "USER_NOT_FOUND", "user not found", ""))`,
+		`errors.MustRegister(errors.NewReasonCoder(int(ErrorReason_DB_ERROR), 500, "DB_ERROR", "", ""))`,
+		"// IsUserNotFound user not found\nfunc IsUserNotFound(err error) bool {\n\treturn errors.IsCode(err, int(ErrorReason_USER_NOT_FOUND))\n}",
+		"// ErrorDbError DB_ERROR\nfunc ErrorDbError(format string, args ...interface{}) error {\n\treturn errors.WithCode(int(ErrorReason_DB_ERROR), format, args...)\n}",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expect generated code contains %q, got:\n%s", want, content)
+		}
+	}
+	// 值为 0 和没有 default_code 的 enum 不生成
+	for _, unwanted := range []string{"UNSPECIFIED", "ACTIVE", "more details"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("unexpected %q in generated code:\n%s", unwanted, content)
+		}
+	}
+}
+
+func TestGenerateFileWithoutOption(t *testing.T) {
+	gen := testFile(t, false)
+	if g := generateFile(gen, gen.Files[len(gen.Files)-1]); g != nil {
+		t.Error("expect no file without (lori.errors.default_code)")
+	}
+	if resp := gen.Response(); len(resp.File) != 0 {
+		t.Errorf("unexpected files %v", resp.File)
+	}
+}
@@ -0,0 +1,37 @@
+// protoc-gen-lori-errors 根据 proto enum 生成 lori 错误码的构造函数和 Is<Reason> 方法.
+//
+//	protoc --proto_path=. --proto_path=$(lori) \
+//	    --go_out=paths=source_relative:. \
+//	    --lori-errors_out=paths=source_relative:. \
+//	    api/user/v1/error_reason.proto
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const version = "v0.1.0"
+
+func main() {
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	flag.Parse()
+	if *showVersion {
+		fmt.Printf("protoc-gen-lori-errors %v\n", version)
+		return
+	}
+
+	protogen.Options{ParamFunc: flag.CommandLine.Set}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+		for _, f := range gen.Files {
+			if !f.Generate {
+				continue
+			}
+			generateFile(gen, f)
+		}
+		return nil
+	})
+}
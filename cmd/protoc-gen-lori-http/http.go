@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	contextPackage = protogen.GoImportPath("context")
+	ginPackage     = protogen.GoImportPath("github.com/gin-gonic/gin")
+	httpPackage    = protogen.GoImportPath("github.com/cr-mao/lori/transport/http")
+)
+
+// route 一个 http 绑定
+type route struct {
+	method       string // GET, "*" 表示任意方法
+	path         string // gin 路由, /v1/user/:id
+	body         string
+	responseBody string
+	hasVars      bool
+}
+
+// httpMethod 带有 google.api.http 注解的方法
+type httpMethod struct {
+	method *protogen.Method
+	routes []route
+}
+
+// generateFile 生成 _http.pb.go, 没有带 google.api.http 注解的方法时不生成文件;
+// 流式方法不支持, 跳过
+func generateFile(gen *protogen.Plugin, file *protogen.File) (*protogen.GeneratedFile, error) {
+	services := make(map[*protogen.Service][]httpMethod)
+	for _, s := range file.Services {
+		for _, m := range s.Methods {
+			if m.Desc.IsStreamingClient() || m.Desc.IsStreamingServer() {
+				continue
+			}
+			rule, ok := proto.GetExtension(m.Desc.Options(), annotations.E_Http).(*annotations.HttpRule)
+			if !ok || rule == nil {
+				continue
+			}
+			routes, err := methodRoutes(m, rule)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", m.Desc.FullName(), err)
+			}
+			services[s] = append(services[s], httpMethod{method: m, routes: routes})
+		}
+	}
+	if len(services) == 0 {
+		return nil, nil
+	}
+
+	g := gen.NewGeneratedFile(file.GeneratedFilenamePrefix+"_http.pb.go", file.GoImportPath)
+	g.P("// Code generated by protoc-gen-lori-http. DO NOT EDIT.")
+	g.P("// versions:")
+	g.P("// - protoc-gen-lori-http ", version)
+	g.P("// source: ", file.Desc.Path())
+	g.P()
+	g.P("package ", file.GoPackageName)
+	g.P()
+	for _, s := range file.Services {
+		if methods, ok := services[s]; ok {
+			generateService(g, s, methods)
+		}
+	}
+	return g, nil
+}
+
+func generateService(g *protogen.GeneratedFile, s *protogen.Service, methods []httpMethod) {
+	serverType := s.GoName + "HTTPServer"
+	g.P("// ", serverType, " ", s.GoName, " 的 http 接口, 与 grpc 使用同一个实现")
+	g.P("type ", serverType, " interface {")
+	for _, hm := range methods {
+		m := hm.method
+		if c := m.Comments.Leading; c != "" {
+			g.P(strings.TrimSuffix(c.String(), "\n"))
+		}
+		g.P(m.GoName, "(", contextPackage.Ident("Context"), ", *", m.Input.GoIdent, ") (*", m.Output.GoIdent, ", error)")
+	}
+	g.P("}")
+	g.P()
+
+	g.P("// Register", serverType, " 注册 google.api.http 定义的路由, r 为 *http.Server 或者它的路由组,")
+	g.P("// 错误通过 c.Error 交给 http.Server 内置的 ErrorHandler 统一返回")
+	g.P("func Register", serverType, "(r ", ginPackage.Ident("IRoutes"), ", srv ", serverType, ") {")
+	for _, hm := range methods {
+		for i, rt := range hm.routes {
+			handler := handlerName(s, hm.method, i)
+			if rt.method == "*" {
+				g.P("r.Any(", strconv.Quote(rt.path), ", ", handler, "(srv))")
+				continue
+			}
+			g.P("r.Handle(", strconv.Quote(rt.method), ", ", strconv.Quote(rt.path), ", ", handler, "(srv))")
+		}
+	}
+	g.P("}")
+	g.P()
+
+	for _, hm := range methods {
+		m := hm.method
+		for i, rt := range hm.routes {
+			g.P("func ", handlerName(s, m, i), "(srv ", serverType, ") ", ginPackage.Ident("HandlerFunc"), " {")
+			g.P("return func(c *", ginPackage.Ident("Context"), ") {")
+			g.P("var in ", m.Input.GoIdent)
+			if rt.body != "" {
+				bind(g, httpPackage.Ident("BindBody"), ", "+strconv.Quote(rt.body))
+			}
+			if rt.body != "*" {
+				bind(g, httpPackage.Ident("BindQuery"), "")
+			}
+			if rt.hasVars {
+				bind(g, httpPackage.Ident("BindVars"), "")
+			}
+			bind(g, httpPackage.Ident("Validate"), "")
+			g.P("out, err := srv.", m.GoName, "(c.Request.Context(), &in)")
+			g.P("if err != nil {")
+			g.P("_ = c.Error(err)")
+			g.P("return")
+			g.P("}")
+			g.P(httpPackage.Ident("Result"), "(c, out, ", strconv.Quote(rt.responseBody), ")")
+			g.P("}")
+			g.P("}")
+			g.P()
+		}
+	}
+}
+
+func bind(g *protogen.GeneratedFile, fn protogen.GoIdent, extra string) {
+	g.P("if err := ", fn, "(c, &in", extra, "); err != nil {")
+	g.P("_ = c.Error(err)")
+	g.P("return")
+	g.P("}")
+}
+
+func handlerName(s *protogen.Service, m *protogen.Method, i int) string {
+	return fmt.Sprintf("_%s_%s%d_HTTP_Handler", s.GoName, m.GoName, i)
+}
+
+// methodRoutes 方法的主绑定和 additional_bindings
+func methodRoutes(m *protogen.Method, rule *annotations.HttpRule) ([]route, error) {
+	rules := append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...)
+	routes := make([]route, 0, len(rules))
+	for _, r := range rules {
+		rt, err := buildRoute(m, r)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, rt)
+	}
+	return routes, nil
+}
+
+func buildRoute(m *protogen.Method, rule *annotations.HttpRule) (route, error) {
+	var method, path string
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		method, path = http.MethodGet, p.Get
+	case *annotations.HttpRule_Put:
+		method, path = http.MethodPut, p.Put
+	case *annotations.HttpRule_Post:
+		method, path = http.MethodPost, p.Post
+	case *annotations.HttpRule_Delete:
+		method, path = http.MethodDelete, p.Delete
+	case *annotations.HttpRule_Patch:
+		method, path = http.MethodPatch, p.Patch
+	case *annotations.HttpRule_Custom:
+		method, path = strings.ToUpper(p.Custom.GetKind()), p.Custom.GetPath()
+	default:
+		return route{}, fmt.Errorf("missing http pattern")
+	}
+	ginPath, vars, err := convertPath(path)
+	if err != nil {
+		return route{}, err
+	}
+	for _, v := range vars {
+		if err = checkFieldPath(m.Input.Desc, v); err != nil {
+			return route{}, fmt.Errorf("path %s: %w", path, err)
+		}
+	}
+	if body := rule.GetBody(); body != "" && body != "*" {
+		if err = checkFieldPath(m.Input.Desc, body); err != nil {
+			return route{}, fmt.Errorf("body: %w", err)
+		}
+	}
+	if rb := rule.GetResponseBody(); rb != "" {
+		if err = checkResponseBody(m.Output.Desc, rb); err != nil {
+			return route{}, fmt.Errorf("response_body: %w", err)
+		}
+	}
+	return route{
+		method:       method,
+		path:         ginPath,
+		body:         rule.GetBody(),
+		responseBody: rule.GetResponseBody(),
+		hasVars:      len(vars) > 0,
+	}, nil
+}
+
+// convertPath google.api.http 路径模板转换为 gin 路由:
+//
+//	/v1/users/{id}         -> /v1/users/:id
+//	/v1/users/{id=*}       -> /v1/users/:id
+//	/v1/files/{path=**}    -> /v1/files/*path (只能在最后)
+//
+// 多段匹配(比如 {name=shelves/*}) 和自定义动词(:cancel) gin 无法表示, 返回错误
+func convertPath(path string) (string, []string, error) {
+	var (
+		b    strings.Builder
+		vars []string
+	)
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '{':
+			end := strings.IndexByte(path[i:], '}')
+			if end < 0 {
+				return "", nil, fmt.Errorf("path %s: unclosed variable", path)
+			}
+			name, pattern, _ := strings.Cut(path[i+1:i+end], "=")
+			i += end
+			switch pattern {
+			case "", "*":
+				b.WriteString(":" + name)
+			case "**":
+				if i != len(path)-1 {
+					return "", nil, fmt.Errorf("path %s: {%s=**} must be the last segment", path, name)
+				}
+				b.WriteString("*" + name)
+			default:
+				return "", nil, fmt.Errorf("path %s: variable pattern %q is not supported", path, pattern)
+			}
+			vars = append(vars, name)
+		case ':', '*':
+			return "", nil, fmt.Errorf("path %s: custom verb and wildcard outside variable are not supported", path)
+		default:
+			b.WriteByte(path[i])
+		}
+	}
+	return b.String(), vars, nil
+}
+
+// checkResponseBody response_body 只能是顶层的非 repeated 消息字段, protobuf 编码时才能作为响应返回
+func checkResponseBody(md protoreflect.MessageDescriptor, name string) error {
+	fd := md.Fields().ByName(protoreflect.Name(name))
+	if fd == nil {
+		return fmt.Errorf("field %s not found in %s", name, md.FullName())
+	}
+	if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+		return fmt.Errorf("field %s in %s is not a singular message", name, md.FullName())
+	}
+	return nil
+}
+
+// checkFieldPath 字段路径(a.b.c)在消息中存在, 中间的字段必须是消息
+func checkFieldPath(md protoreflect.MessageDescriptor, path string) error {
+	parts := strings.Split(path, ".")
+	for i, name := range parts {
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return fmt.Errorf("field %s not found in %s", path, md.FullName())
+		}
+		if i == len(parts)-1 {
+			return nil
+		}
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return fmt.Errorf("field %s in %s is not a message", name, path)
+		}
+		md = fd.Message()
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestConvertPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		vars []string
+	}{
+		{"/v1/greeter", "/v1/greeter", nil},
+		{"/v1/users/{id}", "/v1/users/:id", []string{"id"}},
+		{"/v1/users/{user.id=*}/books/{book}", "/v1/users/:user.id/books/:book", []string{"user.id", "book"}},
+		{"/v1/files/{path=**}", "/v1/files/*path", []string{"path"}},
+	}
+	for _, tt := range tests {
+		got, vars, err := convertPath(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want || !reflect.DeepEqual(vars, tt.vars) {
+			t.Errorf("convertPath(%q) = %q %v, want %q %v", tt.in, got, vars, tt.want, tt.vars)
+		}
+	}
+
+	for _, in := range []string{
+		"/v1/{name=shelves/*}",
+		"/v1/books/{id}:cancel",
+		"/v1/{path=**}/raw",
+		"/v1/{id",
+	} {
+		if _, _, err := convertPath(in); err == nil {
+			t.Errorf("convertPath(%q) expect error", in)
+		}
+	}
+}
+
+func TestCheckResponseBody(t *testing.T) {
+	md := (&descriptorpb.FileDescriptorProto{}).ProtoReflect().Descriptor()
+	if err := checkResponseBody(md, "options"); err != nil {
+		t.Errorf("expect singular message accepted, got %v", err)
+	}
+	for _, name := range []string{"name", "message_type", "options.java_package", "missing"} {
+		if err := checkResponseBody(md, name); err == nil {
+			t.Errorf("checkResponseBody(%q) expect error", name)
+		}
+	}
+}
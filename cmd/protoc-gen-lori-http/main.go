@@ -0,0 +1,40 @@
+// protoc-gen-lori-http 根据 google.api.http 注解生成 gin 路由, http 和 grpc 使用同一个服务实现.
+//
+//	protoc --proto_path=. --proto_path=$(lori)/third_party \
+//	    --go_out=paths=source_relative:. \
+//	    --go-grpc_out=paths=source_relative:. \
+//	    --lori-http_out=paths=source_relative:. \
+//	    api/user/v1/user.proto
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const version = "v0.1.0"
+
+func main() {
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	flag.Parse()
+	if *showVersion {
+		fmt.Printf("protoc-gen-lori-http %v\n", version)
+		return
+	}
+
+	protogen.Options{ParamFunc: flag.CommandLine.Set}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+		for _, f := range gen.Files {
+			if !f.Generate {
+				continue
+			}
+			if _, err := generateFile(gen, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
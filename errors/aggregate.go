@@ -217,6 +217,7 @@ func Reduce(err error) error {
 // AggregateGoroutines runs the provided functions in parallel, stuffing all
 // non-nil errors into the returned Aggregate.
 // Returns nil if all the functions complete successfully.
+// Use Group for a concurrency limit, context cancellation and per-task timeouts.
 func AggregateGoroutines(funcs ...func() error) Aggregate {
 	errChan := make(chan error, len(funcs))
 	for _, f := range funcs {
@@ -39,6 +39,33 @@ type defaultCoder struct {
 	Ref string
 }
 
+// NewCoder returns a Coder with the given code, http status, external message and reference,
+// used by the code generated by cmd/codegen.
+func NewCoder(code int, httpStatus int, ext string, ref string) Coder {
+	return defaultCoder{C: code, HTTP: httpStatus, Ext: ext, Ref: ref}
+}
+
+// Reasoner is an optional interface of Coder, the reason is a readable name of the code,
+// such as the proto enum name, and is carried in grpc status ErrorInfo.Reason.
+type Reasoner interface {
+	Reason() string
+}
+
+type reasonCoder struct {
+	defaultCoder
+	reason string
+}
+
+// NewReasonCoder returns a Coder with a reason, used by the code generated by protoc-gen-lori-errors.
+func NewReasonCoder(code int, httpStatus int, reason string, ext string, ref string) Coder {
+	return reasonCoder{defaultCoder: defaultCoder{C: code, HTTP: httpStatus, Ext: ext, Ref: ref}, reason: reason}
+}
+
+// Reason returns the reason of the coder.
+func (coder reasonCoder) Reason() string {
+	return coder.reason
+}
+
 // Code returns the integer code of the coder.
 func (coder defaultCoder) Code() int {
 	return coder.C
@@ -109,9 +136,7 @@ func ParseCoder(err error) Coder {
 	}
 
 	if v, ok := err.(*withCode); ok {
-		if coder, ok := codes[v.code]; ok {
-			return coder
-		}
+		return v.coder()
 	}
 
 	return unknownCoder
@@ -96,7 +96,6 @@ import (
 	"fmt"
 	"io"
 
-	gCode "google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
@@ -304,6 +303,16 @@ type withCode struct {
 	code  int
 	cause error
 	*stack
+
+	// status and remote keep the peer's status and coder for errors restored from a gRPC status.
+	status *status.Status
+	remote Coder
+
+	// fields holds structured fields as key, value, key, value...
+	fields []interface{}
+
+	// violations holds the invalid request fields.
+	violations []FieldViolation
 }
 
 func WithCode(code int, format string, args ...interface{}) error {
@@ -314,6 +323,17 @@ func WithCode(code int, format string, args ...interface{}) error {
 	}
 }
 
+// WithCoder returns a coded error carrying its own coder, the coder is used when
+// the code is not registered locally, e.g. errors decoded from a remote service.
+func WithCoder(coder Coder, format string, args ...interface{}) error {
+	return &withCode{
+		err:    fmt.Errorf(format, args...),
+		code:   coder.Code(),
+		stack:  callers(),
+		remote: coder,
+	}
+}
+
 func WrapC(err error, code int, format string, args ...interface{}) error {
 	if err == nil {
 		return nil
@@ -366,32 +386,3 @@ func Cause(err error) error {
 	}
 	return err
 }
-
-func FromGrpcError(e error) error {
-	if e == nil {
-		return e
-	}
-
-	st, ok := status.FromError(e)
-	if !ok {
-		return WithCode(100002, "unknown error")
-	}
-
-	return &withCode{
-		err:  st.Err(),
-		code: int(st.Code()),
-	}
-}
-
-func ToGrpcError(e error) error {
-	if e == nil {
-		return e
-	}
-
-	var perr *withCode
-	if As(e, &perr) {
-		err := status.Error(gCode.Code(perr.code), perr.err.Error())
-		return err
-	}
-	return status.Error(gCode.Unknown, e.Error())
-}
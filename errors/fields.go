@@ -0,0 +1,65 @@
+package errors
+
+import "fmt"
+
+// WithFields attaches structured fields to err. keyvals alternate key and value, as in log.Logger.
+// If err is a withCode, a copy with the fields appended is returned; otherwise err is wrapped
+// in a withCode with the unknown code.
+//
+//	err = errors.WithFields(err, "user_id", uid, "order_id", oid)
+func WithFields(err error, keyvals ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, "KEYVALS UNPAIRED")
+	}
+	w, ok := err.(*withCode)
+	if !ok {
+		return &withCode{
+			err:    err,
+			code:   unknownCoder.Code(),
+			cause:  err,
+			stack:  callers(),
+			fields: keyvals,
+		}
+	}
+	c := *w
+	c.fields = make([]interface{}, 0, len(w.fields)+len(keyvals))
+	c.fields = append(c.fields, w.fields...)
+	c.fields = append(c.fields, keyvals...)
+	return &c
+}
+
+// Fields returns the structured fields of every error in the chain; outer fields override inner
+// fields with the same key. The result alternates key and value and can be passed to log.Logger.Log.
+func Fields(err error) []interface{} {
+	var kvs []interface{}
+	seen := make(map[string]bool)
+	for _, e := range list(err) {
+		w, ok := e.(*withCode)
+		if !ok {
+			continue
+		}
+		for i := 0; i+1 < len(w.fields); i += 2 {
+			key := fmt.Sprint(w.fields[i])
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			kvs = append(kvs, key, w.fields[i+1])
+		}
+	}
+	return kvs
+}
+
+func fieldsMap(keyvals []interface{}) map[string]interface{} {
+	if len(keyvals) == 0 {
+		return nil
+	}
+	m := make(map[string]interface{}, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		m[fmt.Sprint(keyvals[i])] = keyvals[i+1]
+	}
+	return m
+}
@@ -0,0 +1,29 @@
+package errors
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithFields(t *testing.T) {
+	inner := WithFields(WithCode(ErrUserNotFound, "user not found"), "user_id", 1, "db", "user")
+	outer := WithFields(WrapC(inner, ErrUserEncode, "encode failed"), "user_id", 2)
+
+	want := []interface{}{"user_id", 2, "db", "user"}
+	if got := Fields(outer); !reflect.DeepEqual(got, want) {
+		t.Errorf("expect fields %v, got %v", want, got)
+	}
+	if !IsCode(outer, ErrUserNotFound) || !IsCode(outer, ErrUserEncode) {
+		t.Errorf("fields should not change the error chain: %+v", outer)
+	}
+	if got := fmt.Sprintf("%#v", inner); !strings.Contains(got, `"fields":{"db":"user","user_id":1}`) {
+		t.Errorf("unexpected json output %s", got)
+	}
+
+	plain := WithFields(fmt.Errorf("dial failed"), "addr", "10.0.0.1")
+	if ParseCoder(plain).Code() != unknownCoder.Code() || !reflect.DeepEqual(Fields(plain), []interface{}{"addr", "10.0.0.1"}) {
+		t.Errorf("unexpected plain error %#v", plain)
+	}
+}
@@ -13,6 +13,7 @@ type formatInfo struct {
 	message string
 	err     string
 	stack   *stack
+	fields  map[string]interface{}
 }
 
 // Format implements fmt.Formatter. https://golang.org/pkg/fmt/#hdr-Printing
@@ -36,6 +37,7 @@ type formatInfo struct {
 //	%-v:   error for internal read B - #0 [/xxx/main.go:12 (main.main)] (#100102) Internal Server Error
 //	%+v:   error for internal read B - #0 [/xxx/main.go:12 (main.main)] (#100102) Internal Server Error; error for internal read A - #1 [/xxx/main.go:35 (main.newErrorB)] (#100104) Validation failed
 //	%#v:   [{"error":"error for internal read B"}]
+//	%#v:   [{"error":"error for internal read B","fields":{"user_id":1}}] (with fields attached by WithFields)
 //	%#-v:  [{"caller":"#0 /xxx/main.go:12 (main.main)","error":"error for internal read B","message":"(#100102) Internal Server Error"}]
 //	%#+v:  [{"caller":"#0 /xxx/main.go:12 (main.main)","error":"error for internal read B","message":"(#100102) Internal Server Error"},{"caller":"#1 /xxx/main.go:35 (main.newErrorB)","error":"error for internal read A","message":"(#100104) Validation failed"}]
 func (w *withCode) Format(state fmt.State, verb rune) {
@@ -117,6 +119,9 @@ func format(k int, jsonData []map[string]interface{}, str *bytes.Buffer, finfo *
 		} else {
 			data["error"] = finfo.message
 		}
+		if len(finfo.fields) > 0 {
+			data["fields"] = finfo.fields
+		}
 		jsonData = append(jsonData, data)
 	} else {
 		if flagDetail || flagTrace {
@@ -179,10 +184,7 @@ func buildFormatInfo(e error) *formatInfo {
 			stack:   err.stack,
 		}
 	case *withCode:
-		coder, ok := codes[err.code]
-		if !ok {
-			coder = unknownCoder
-		}
+		coder := err.coder()
 
 		extMsg := coder.String()
 		if extMsg == "" {
@@ -194,6 +196,7 @@ func buildFormatInfo(e error) *formatInfo {
 			message: extMsg,
 			err:     err.err.Error(),
 			stack:   err.stack,
+			fields:  fieldsMap(err.fields),
 		}
 	default:
 		finfo = &formatInfo{
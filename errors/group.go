@@ -0,0 +1,228 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Group is a goroutine group with a concurrency limit, context support, fail-fast or collect-all
+// error handling, panic recovery and per-task timeouts.
+// Every error in the Aggregate returned by Wait is a *TaskError labelled with its task.
+//
+//	g, ctx := errors.NewGroup(ctx, errors.WithGroupLimit(8))
+//	for _, id := range ids {
+//		id := id
+//		g.Go(fmt.Sprintf("user:%d", id), func(ctx context.Context) error {
+//			return loadUser(ctx, id)
+//		}, errors.WithTaskTimeout(time.Second))
+//	}
+//	if agg := g.Wait(); agg != nil {
+//		...
+//	}
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	opts   groupOptions
+	sem    chan struct{}
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	errs   []error // in the order of the Go calls
+	failed bool
+}
+
+type groupOptions struct {
+	limit    int
+	failFast bool
+	timeout  time.Duration
+}
+
+// GroupOption configures a Group.
+type GroupOption func(*groupOptions)
+
+// WithGroupLimit limits the number of concurrent tasks; <=0 means no limit.
+func WithGroupLimit(n int) GroupOption {
+	return func(o *groupOptions) {
+		o.limit = n
+	}
+}
+
+// WithGroupFailFast cancels ctx on the first error; tasks that have not started are skipped and Wait
+// returns only the first error. By default the errors of all tasks are collected.
+func WithGroupFailFast() GroupOption {
+	return func(o *groupOptions) {
+		o.failFast = true
+	}
+}
+
+// WithGroupTimeout sets the default timeout of each task.
+func WithGroupTimeout(timeout time.Duration) GroupOption {
+	return func(o *groupOptions) {
+		o.timeout = timeout
+	}
+}
+
+// TaskOption configures a single task.
+type TaskOption func(*taskOptions)
+
+type taskOptions struct {
+	timeout time.Duration
+}
+
+// WithTaskTimeout sets the timeout of a task, overriding WithGroupTimeout.
+func WithTaskTimeout(timeout time.Duration) TaskOption {
+	return func(o *taskOptions) {
+		o.timeout = timeout
+	}
+}
+
+// NewGroup returns a Group and a ctx that is canceled on fail-fast or when Wait returns.
+func NewGroup(ctx context.Context, opts ...GroupOption) (*Group, context.Context) {
+	o := groupOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	g := &Group{ctx: ctx, cancel: cancel, opts: o}
+	if o.limit > 0 {
+		g.sem = make(chan struct{}, o.limit)
+	}
+	return g, ctx
+}
+
+// Go starts a task, blocking while the concurrency limit is reached until a slot frees up or ctx is
+// canceled. If ctx is already canceled the task is skipped and ctx's error is recorded, unless the
+// cancellation was caused by fail-fast.
+func (g *Group) Go(label string, fn func(ctx context.Context) error, opts ...TaskOption) {
+	to := taskOptions{timeout: g.opts.timeout}
+	for _, opt := range opts {
+		opt(&to)
+	}
+
+	g.mu.Lock()
+	idx := len(g.errs)
+	g.errs = append(g.errs, nil)
+	g.mu.Unlock()
+
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		case <-g.ctx.Done():
+			g.done(idx, label, g.ctx.Err())
+			return
+		}
+	}
+	if err := g.ctx.Err(); err != nil {
+		g.release()
+		g.done(idx, label, err)
+		return
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer g.release()
+		g.done(idx, label, g.run(fn, to))
+	}()
+}
+
+func (g *Group) run(fn func(ctx context.Context) error, to taskOptions) (err error) {
+	ctx := g.ctx
+	if to.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, to.timeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, stack: callers()}
+		}
+	}()
+	return fn(ctx)
+}
+
+func (g *Group) release() {
+	if g.sem != nil {
+		<-g.sem
+	}
+}
+
+func (g *Group) done(idx int, label string, err error) {
+	if err == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.opts.failFast {
+		if g.failed {
+			return
+		}
+		g.failed = true
+		g.cancel()
+	}
+	g.errs[idx] = &TaskError{Label: label, Err: err}
+}
+
+// Wait waits for all tasks and returns nil if none failed.
+func (g *Group) Wait() Aggregate {
+	g.wg.Wait()
+	g.cancel()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return NewAggregate(g.errs)
+}
+
+// TaskError is an error labelled with its task.
+type TaskError struct {
+	Label string
+	Err   error
+}
+
+func (e *TaskError) Error() string { return e.Label + ": " + e.Err.Error() }
+
+// Unwrap provides compatibility for Go 1.13 error chains.
+func (e *TaskError) Unwrap() error { return e.Err }
+
+// Format prints the details of the inner error for %+v.
+func (e *TaskError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%s: %+v", e.Label, e.Err)
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+// PanicError is the error recovered from a task panic, with the stack of the panic.
+type PanicError struct {
+	Value interface{}
+	*stack
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("panic: %v", e.Value) }
+
+// Format prints the stack for %+v.
+func (e *PanicError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			io.WriteString(s, e.Error())
+			e.stack.Format(s, verb)
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
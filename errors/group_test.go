@@ -0,0 +1,97 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupLimit(t *testing.T) {
+	var running, max int32
+	g, _ := NewGroup(context.Background(), WithGroupLimit(2))
+	for i := 0; i < 6; i++ {
+		g.Go(fmt.Sprintf("task-%d", i), func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return nil
+		})
+	}
+	if agg := g.Wait(); agg != nil {
+		t.Fatal(agg)
+	}
+	if max > 2 {
+		t.Errorf("expect at most 2 concurrent tasks, got %d", max)
+	}
+}
+
+func TestGroupCollectAll(t *testing.T) {
+	g, _ := NewGroup(context.Background())
+	g.Go("a", func(ctx context.Context) error { return WithCode(ErrUserNotFound, "a failed") })
+	g.Go("b", func(ctx context.Context) error { return nil })
+	g.Go("c", func(ctx context.Context) error { panic("c crashed") })
+
+	agg := g.Wait()
+	if agg == nil || len(agg.Errors()) != 2 {
+		t.Fatalf("expect 2 errors, got %v", agg)
+	}
+	a, ok := agg.Errors()[0].(*TaskError)
+	if !ok || a.Label != "a" || !IsCode(a.Err, ErrUserNotFound) {
+		t.Errorf("unexpected first error %#v", agg.Errors()[0])
+	}
+	var perr *PanicError
+	if !As(agg.Errors()[1], &perr) || perr.Value != "c crashed" {
+		t.Fatalf("expect panic error, got %v", agg.Errors()[1])
+	}
+	if got := fmt.Sprintf("%+v", agg.Errors()[1]); !strings.Contains(got, "c: panic: c crashed") || !strings.Contains(got, "group_test.go") {
+		t.Errorf("panic error should carry the stack: %s", got)
+	}
+}
+
+func TestGroupFailFast(t *testing.T) {
+	g, ctx := NewGroup(context.Background(), WithGroupLimit(1), WithGroupFailFast())
+	var ran int32
+	g.Go("first", func(ctx context.Context) error { return fmt.Errorf("boom") })
+	for i := 0; i < 3; i++ {
+		g.Go("rest", func(ctx context.Context) error {
+			atomic.AddInt32(&ran, 1)
+			return ctx.Err()
+		})
+	}
+	agg := g.Wait()
+	if agg == nil || len(agg.Errors()) != 1 || agg.Errors()[0].Error() != "first: boom" {
+		t.Fatalf("expect only the first error, got %v", agg)
+	}
+	if ctx.Err() == nil {
+		t.Error("group context should be canceled")
+	}
+	if ran > 0 {
+		t.Errorf("tasks after the failure should not run, ran %d", ran)
+	}
+}
+
+func TestGroupTaskTimeout(t *testing.T) {
+	g, _ := NewGroup(context.Background(), WithGroupTimeout(time.Second))
+	g.Go("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTaskTimeout(10*time.Millisecond))
+
+	start := time.Now()
+	agg := g.Wait()
+	if agg == nil || !Is(agg.Errors()[0], context.DeadlineExceeded) {
+		t.Fatalf("expect deadline exceeded, got %v", agg)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("task timeout should override group timeout")
+	}
+}
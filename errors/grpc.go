@@ -0,0 +1,249 @@
+package errors
+
+import (
+	"net/http"
+	"strconv"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	gCode "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+)
+
+// ErrorInfoDomain is the domain of the ErrorInfo in gRPC status details that carries a lori error code.
+const ErrorInfoDomain = "lori"
+
+// Keys used in ErrorInfo.Metadata.
+const (
+	errorInfoCode      = "code"
+	errorInfoHTTP      = "http"
+	errorInfoMessage   = "message"   // internal message; only read from older peers, never sent
+	errorInfoExternal  = "external"  // external message, Coder.String()
+	errorInfoReference = "reference" // documentation URL
+)
+
+// GRPCStatus implements the interface used by status.FromError and status.Code, so they recognize lori error codes.
+func (w *withCode) GRPCStatus() *status.Status {
+	if w.status != nil {
+		return w.status
+	}
+	return ToGrpcStatus(w)
+}
+
+// coder returns the registered Coder of the code, falling back to the one sent by the peer.
+func (w *withCode) coder() Coder {
+	codeMux.Lock()
+	coder, ok := codes[w.code]
+	codeMux.Unlock()
+	if ok {
+		return coder
+	}
+	if w.remote != nil {
+		return w.remote
+	}
+	return unknownCoder
+}
+
+// ToGrpcStatus converts err to a gRPC status. The lori error code is carried in an ErrorInfo detail
+// whose Reason is the Reasoner's reason, or the code when there is none. The gRPC code is mapped from
+// the http status of the code.
+// The status message is the external message Coder.String(); internal messages (the WithCode format,
+// panic values and so on) are never sent to the peer. Errors that are neither lori errors nor gRPC
+// statuses become Unknown with a generic message.
+func ToGrpcStatus(e error) *status.Status {
+	if e == nil {
+		return nil
+	}
+	var perr *withCode
+	if !As(e, &perr) {
+		if st, ok := status.FromError(e); ok {
+			return st
+		}
+		return status.New(gCode.Unknown, unknownCoder.String())
+	}
+	if perr.status != nil {
+		return perr.status
+	}
+	coder := perr.coder()
+	st := status.New(GRPCCodeFromHTTP(coder.HTTPStatus()), coder.String())
+	reason := strconv.Itoa(perr.code)
+	if r, ok := coder.(Reasoner); ok && r.Reason() != "" {
+		reason = r.Reason()
+	}
+	info := &errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: ErrorInfoDomain,
+		Metadata: map[string]string{
+			errorInfoCode:      strconv.Itoa(perr.code),
+			errorInfoHTTP:      strconv.Itoa(coder.HTTPStatus()),
+			errorInfoExternal:  coder.String(),
+			errorInfoReference: coder.Reference(),
+		},
+	}
+	details := []protoiface.MessageV1{info}
+	if violations := Violations(e); len(violations) > 0 {
+		details = append(details, toBadRequest(violations))
+	}
+	if ds, err := st.WithDetails(details...); err == nil {
+		return ds
+	}
+	return st
+}
+
+func toBadRequest(violations []FieldViolation) *errdetails.BadRequest {
+	br := &errdetails.BadRequest{FieldViolations: make([]*errdetails.BadRequest_FieldViolation, 0, len(violations))}
+	for _, v := range violations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+	return br
+}
+
+func fromBadRequest(details []interface{}) []FieldViolation {
+	var violations []FieldViolation
+	for _, detail := range details {
+		br, ok := detail.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+		for _, v := range br.GetFieldViolations() {
+			violations = append(violations, FieldViolation{Field: v.GetField(), Description: v.GetDescription()})
+		}
+	}
+	return violations
+}
+
+// ToGrpcError converts err to a gRPC error, see ToGrpcStatus.
+func ToGrpcError(e error) error {
+	if e == nil {
+		return e
+	}
+	return ToGrpcStatus(e).Err()
+}
+
+// FromGrpcError converts a gRPC error back to a lori error. If the details carry a lori ErrorInfo,
+// the original code is restored for IsCode and ParseCoder; otherwise the gRPC code is used as the code.
+func FromGrpcError(e error) error {
+	if e == nil {
+		return e
+	}
+
+	st, ok := status.FromError(e)
+	if !ok {
+		return WithCode(100002, "unknown error")
+	}
+	if perr, ok := e.(*withCode); ok && perr.status != nil {
+		return perr
+	}
+
+	details := st.Details()
+	violations := fromBadRequest(details)
+	for _, detail := range details {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.GetDomain() != ErrorInfoDomain {
+			continue
+		}
+		md := info.GetMetadata()
+		code, err := strconv.Atoi(md[errorInfoCode])
+		if err != nil {
+			continue
+		}
+		httpStatus, _ := strconv.Atoi(md[errorInfoHTTP])
+		msg, ok := md[errorInfoMessage]
+		if !ok {
+			msg = st.Message()
+		}
+		var remote Coder = defaultCoder{
+			C:    code,
+			HTTP: httpStatus,
+			Ext:  md[errorInfoExternal],
+			Ref:  md[errorInfoReference],
+		}
+		if reason := info.GetReason(); reason != "" && reason != md[errorInfoCode] {
+			remote = NewReasonCoder(code, httpStatus, reason, md[errorInfoExternal], md[errorInfoReference])
+		}
+		return &withCode{
+			err:        New(msg),
+			code:       code,
+			stack:      callers(),
+			status:     st,
+			remote:     remote,
+			violations: violations,
+		}
+	}
+
+	return &withCode{
+		err:        st.Err(),
+		code:       int(st.Code()),
+		stack:      callers(),
+		status:     st,
+		violations: violations,
+	}
+}
+
+// GRPCCodeFromHTTP maps an http status to a gRPC code.
+func GRPCCodeFromHTTP(httpStatus int) gCode.Code {
+	switch httpStatus {
+	case http.StatusOK:
+		return gCode.OK
+	case http.StatusBadRequest:
+		return gCode.InvalidArgument
+	case http.StatusUnauthorized:
+		return gCode.Unauthenticated
+	case http.StatusForbidden:
+		return gCode.PermissionDenied
+	case http.StatusNotFound:
+		return gCode.NotFound
+	case http.StatusConflict:
+		return gCode.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return gCode.FailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return gCode.ResourceExhausted
+	case 499:
+		return gCode.Canceled
+	case http.StatusNotImplemented:
+		return gCode.Unimplemented
+	case http.StatusServiceUnavailable:
+		return gCode.Unavailable
+	case http.StatusGatewayTimeout:
+		return gCode.DeadlineExceeded
+	}
+	if httpStatus >= 400 && httpStatus < 500 {
+		return gCode.FailedPrecondition
+	}
+	return gCode.Internal
+}
+
+// HTTPFromGRPCCode maps a gRPC code to an http status.
+func HTTPFromGRPCCode(code gCode.Code) int {
+	switch code {
+	case gCode.OK:
+		return http.StatusOK
+	case gCode.Canceled:
+		return 499
+	case gCode.InvalidArgument, gCode.OutOfRange:
+		return http.StatusBadRequest
+	case gCode.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case gCode.NotFound:
+		return http.StatusNotFound
+	case gCode.AlreadyExists, gCode.Aborted:
+		return http.StatusConflict
+	case gCode.PermissionDenied:
+		return http.StatusForbidden
+	case gCode.Unauthenticated:
+		return http.StatusUnauthorized
+	case gCode.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case gCode.FailedPrecondition:
+		return http.StatusBadRequest
+	case gCode.Unimplemented:
+		return http.StatusNotImplemented
+	case gCode.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
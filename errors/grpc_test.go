@@ -0,0 +1,133 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	gCode "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	errGrpcUserNotFound = 110100
+	errGrpcRemoteOnly   = 110101
+)
+
+func TestGrpcErrorRoundTrip(t *testing.T) {
+	Register(&ErrCode{C: errGrpcUserNotFound, HTTP: http.StatusNotFound, Ext: "user not found"})
+
+	gerr := ToGrpcError(WithCode(errGrpcUserNotFound, "user %d not found", 1))
+	if got := status.Code(gerr); got != gCode.NotFound {
+		t.Fatalf("expect grpc code %v, got %v", gCode.NotFound, got)
+	}
+
+	err := FromGrpcError(gerr)
+	if !IsCode(err, errGrpcUserNotFound) {
+		t.Fatalf("expect code %d, got %+v", errGrpcUserNotFound, err)
+	}
+	if got := ParseCoder(err).HTTPStatus(); got != http.StatusNotFound {
+		t.Errorf("expect http status %d, got %d", http.StatusNotFound, got)
+	}
+	if got := err.Error(); got != "user not found" {
+		t.Errorf("expect external message, got %q", got)
+	}
+	if got := status.Code(err); got != gCode.NotFound {
+		t.Errorf("expect grpc code %v, got %v", gCode.NotFound, got)
+	}
+}
+
+func TestFromGrpcErrorRemoteCoder(t *testing.T) {
+	// The code is registered by the peer but not locally, so the coder comes from the details.
+	Register(&ErrCode{C: errGrpcRemoteOnly, HTTP: http.StatusForbidden, Ext: "no permission", Ref: "http://doc"})
+	gerr := ToGrpcError(WithCode(errGrpcRemoteOnly, "forbidden"))
+	codeMux.Lock()
+	delete(codes, errGrpcRemoteOnly)
+	codeMux.Unlock()
+
+	coder := ParseCoder(FromGrpcError(gerr))
+	if coder.Code() != errGrpcRemoteOnly || coder.HTTPStatus() != http.StatusForbidden ||
+		coder.String() != "no permission" || coder.Reference() != "http://doc" {
+		t.Errorf("unexpected coder %+v", coder)
+	}
+}
+
+func TestFromGrpcErrorPlainStatus(t *testing.T) {
+	err := FromGrpcError(status.Error(gCode.Unavailable, "unavailable"))
+	if !IsCode(err, int(gCode.Unavailable)) {
+		t.Errorf("expect code %d, got %+v", gCode.Unavailable, err)
+	}
+	if got := status.Code(err); got != gCode.Unavailable {
+		t.Errorf("expect grpc code %v, got %v", gCode.Unavailable, got)
+	}
+}
+
+func TestGrpcErrorReason(t *testing.T) {
+	const code = 110102
+	Register(NewReasonCoder(code, http.StatusBadRequest, "USER_NAME_REQUIRED", "user name is required", ""))
+
+	st := ToGrpcStatus(WithCode(code, "name is empty"))
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	if !ok || info.Reason != "USER_NAME_REQUIRED" {
+		t.Fatalf("unexpected details %+v", st.Details())
+	}
+
+	codeMux.Lock()
+	delete(codes, code)
+	codeMux.Unlock()
+	r, ok := ParseCoder(FromGrpcError(st.Err())).(Reasoner)
+	if !ok || r.Reason() != "USER_NAME_REQUIRED" {
+		t.Errorf("expect remote coder with reason, got %+v", r)
+	}
+}
+
+func TestGrpcErrorViolations(t *testing.T) {
+	const code = 110103
+	Register(NewCoder(code, http.StatusBadRequest, "invalid argument", ""))
+
+	violations := []FieldViolation{{Field: "name", Description: "value length must be at least 1 runes"}}
+	err := WithViolations(WrapC(New("invalid"), code, "invalid user"), violations...)
+	if got := Violations(Wrap(err, "create user")); len(got) != 1 || got[0] != violations[0] {
+		t.Fatalf("unexpected violations %+v", got)
+	}
+
+	st := ToGrpcStatus(err)
+	if st.Code() != gCode.InvalidArgument {
+		t.Errorf("expect grpc code %v, got %v", gCode.InvalidArgument, st.Code())
+	}
+	br, ok := st.Details()[1].(*errdetails.BadRequest)
+	if !ok || len(br.FieldViolations) != 1 || br.FieldViolations[0].Field != "name" {
+		t.Fatalf("unexpected details %+v", st.Details())
+	}
+
+	got := FromGrpcError(st.Err())
+	if !IsCode(got, code) || len(Violations(got)) != 1 || Violations(got)[0] != violations[0] {
+		t.Errorf("expect violations restored, got %+v %+v", got, Violations(got))
+	}
+}
+
+func TestToGrpcStatusHidesInternalMessage(t *testing.T) {
+	const code = 110104
+	Register(NewCoder(code, http.StatusInternalServerError, "internal error", ""))
+
+	st := ToGrpcStatus(WithCode(code, "panic: boom"))
+	if st.Message() != "internal error" {
+		t.Errorf("expect external message, got %q", st.Message())
+	}
+	info := st.Details()[0].(*errdetails.ErrorInfo)
+	for k, v := range info.Metadata {
+		if v == "panic: boom" {
+			t.Errorf("internal message leaked in metadata %q", k)
+		}
+	}
+	if got := fmt.Sprintf("%-v", FromGrpcError(st.Err())); strings.Contains(got, "boom") {
+		t.Errorf("internal message leaked, got %q", got)
+	}
+
+	st = ToGrpcStatus(fmt.Errorf("dial tcp 10.0.0.1:3306: connection refused"))
+	if st.Code() != gCode.Unknown || st.Message() != unknownCoder.String() {
+		t.Errorf("expect unknown status without internal message, got %v %q", st.Code(), st.Message())
+	}
+}
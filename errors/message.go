@@ -0,0 +1,99 @@
+package errors
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// messages is the catalog of localized external messages, language -> code -> message.
+// Languages without an entry fall back to the English message registered by Coder.String().
+var (
+	messages   = map[string]map[int]string{}
+	messageMux = &sync.RWMutex{}
+)
+
+// RegisterMessages registers messages for a language, replacing existing messages of the same codes.
+//
+//	errors.RegisterMessages("zh-CN", map[int]string{
+//		ErrUserNotFound: "用户不存在",
+//	})
+func RegisterMessages(lang string, msgs map[int]string) {
+	lang = normalizeLanguage(lang)
+	messageMux.Lock()
+	defer messageMux.Unlock()
+
+	m, ok := messages[lang]
+	if !ok {
+		m = make(map[int]string, len(msgs))
+		messages[lang] = m
+	}
+	for code, msg := range msgs {
+		m[code] = msg
+	}
+}
+
+// LocalizedString returns the external message of coder for the first matching language; zh-CN
+// falls back to zh, and coder.String() is returned when no language matches.
+func LocalizedString(coder Coder, langs ...string) string {
+	messageMux.RLock()
+	defer messageMux.RUnlock()
+
+	for _, lang := range langs {
+		lang = normalizeLanguage(lang)
+		for lang != "" {
+			if msg, ok := messages[lang][coder.Code()]; ok {
+				return msg
+			}
+			i := strings.LastIndex(lang, "-")
+			if i < 0 {
+				break
+			}
+			lang = lang[:i]
+		}
+	}
+	return coder.String()
+}
+
+// ParseAcceptLanguage parses an Accept-Language header and returns the languages by descending q value.
+//
+//	zh-CN,zh;q=0.9,en;q=0.8 -> [zh-CN zh en]
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	var ws []weighted
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lang, q := part, 1.0
+		if i := strings.Index(part, ";"); i >= 0 {
+			lang = strings.TrimSpace(part[:i])
+			if v := strings.TrimSpace(part[i+1:]); strings.HasPrefix(v, "q=") {
+				if f, err := strconv.ParseFloat(v[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if lang == "" || lang == "*" || q <= 0 {
+			continue
+		}
+		ws = append(ws, weighted{lang: lang, q: q})
+	}
+	sort.SliceStable(ws, func(i, j int) bool { return ws[i].q > ws[j].q })
+
+	langs := make([]string, 0, len(ws))
+	for _, w := range ws {
+		langs = append(langs, w.lang)
+	}
+	return langs
+}
+
+// zh_CN, zh-cn -> zh-cn
+func normalizeLanguage(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+}
@@ -0,0 +1,37 @@
+package errors
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestLocalizedString(t *testing.T) {
+	const code = 110200
+	coder := NewCoder(code, http.StatusNotFound, "Order not found", "")
+	RegisterMessages("zh", map[int]string{code: "订单不存在"})
+	RegisterMessages("zh_TW", map[int]string{code: "訂單不存在"})
+
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"zh-CN,zh;q=0.9,en;q=0.8", "订单不存在"},
+		{"zh-TW", "訂單不存在"},
+		{"fr;q=0.5,zh-TW;q=0.8", "訂單不存在"},
+		{"fr", "Order not found"},
+		{"", "Order not found"},
+	}
+	for _, tt := range tests {
+		if got := LocalizedString(coder, ParseAcceptLanguage(tt.accept)...); got != tt.want {
+			t.Errorf("%q: expect %q, got %q", tt.accept, tt.want, got)
+		}
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	want := []string{"en-US", "zh", "en"}
+	if got := ParseAcceptLanguage("zh;q=0.9, en;q=0.5, *;q=0.1, en-US, fr;q=0"); !reflect.DeepEqual(got, want) {
+		t.Errorf("expect %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,46 @@
+package report
+
+import "strings"
+
+const redacted = "[REDACTED]"
+
+// secretHeaders are redacted headers, in lower case.
+var secretHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"x-api-key":           true,
+}
+
+// secretWords redacts headers whose names contain any of these words.
+var secretWords = []string{"token", "secret", "password", "passwd", "session", "signature"}
+
+// Headers copies header with sensitive values redacted. Both http.Header and gRPC metadata.MD are accepted.
+func Headers(header map[string][]string) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(header))
+	for k, v := range header {
+		if isSecret(k) {
+			out[k] = redacted
+			continue
+		}
+		out[k] = strings.Join(v, ",")
+	}
+	return out
+}
+
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	if secretHeaders[key] {
+		return true
+	}
+	for _, w := range secretWords {
+		if strings.Contains(key, w) {
+			return true
+		}
+	}
+	return false
+}
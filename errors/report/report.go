@@ -0,0 +1,377 @@
+// Package report reports panics and 5xx/INTERNAL errors. Reports are deduplicated by fingerprint,
+// rate limited globally and sent asynchronously to sinks such as files and webhooks.
+//
+//	r := report.NewReporter(
+//		report.WithVersion(version),
+//		report.WithSinks(report.NewWebhookSink("https://hooks.example.com/lori")),
+//	)
+//	report.SetDefault(r)
+//	lori.AfterStop(r.Close)
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/log"
+	"github.com/cr-mao/lori/ratelimit/bucket"
+)
+
+// Kind is the type of a report.
+type Kind string
+
+const (
+	KindPanic Kind = "panic"
+	KindError Kind = "error"
+)
+
+// Report is a single panic or error report.
+type Report struct {
+	Kind        Kind              `json:"kind"`
+	Fingerprint string            `json:"fingerprint"`
+	Message     string            `json:"message"`
+	Code        int               `json:"code,omitempty"`
+	Stack       string            `json:"stack,omitempty"`
+	Operation   string            `json:"operation,omitempty"` // gRPC full method or http route
+	Headers     map[string]string `json:"headers,omitempty"`   // request headers with secrets redacted
+	TraceID     string            `json:"trace_id,omitempty"`
+	Service     string            `json:"service,omitempty"`
+	Version     string            `json:"version,omitempty"`
+	Host        string            `json:"host,omitempty"`
+	Time        time.Time         `json:"time"`
+	// FirstSeen is set the first time this process sees the fingerprint; together with Version it
+	// shows which panics are new in a release.
+	FirstSeen bool `json:"first_seen"`
+	// Count is the number of occurrences merged in the dedup window, including this one.
+	Count int `json:"count"`
+}
+
+// Reporter receives panic and error reports.
+type Reporter interface {
+	Report(ctx context.Context, r *Report)
+}
+
+// Sink is a destination for reports.
+type Sink interface {
+	Send(ctx context.Context, r *Report) error
+}
+
+type options struct {
+	sinks    []Sink
+	service  string
+	version  string
+	interval time.Duration // minimum interval between reports of a fingerprint
+	rate     float64       // maximum reports per second
+	burst    int
+	buffer   int
+	timeout  time.Duration // timeout of each send
+}
+
+// Option configures a DefaultReporter.
+type Option func(*options)
+
+// WithSinks adds destinations for reports.
+func WithSinks(sinks ...Sink) Option {
+	return func(o *options) {
+		o.sinks = append(o.sinks, sinks...)
+	}
+}
+
+// WithService sets the service name.
+func WithService(service string) Option {
+	return func(o *options) {
+		o.service = service
+	}
+}
+
+// WithVersion sets the service version, to tell which release introduced a problem.
+func WithVersion(version string) Option {
+	return func(o *options) {
+		o.version = version
+	}
+}
+
+// WithInterval reports a fingerprint at most once per interval; occurrences in between are added to
+// the Count of the next report. Defaults to 1 minute.
+func WithInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.interval = interval
+	}
+}
+
+// WithRateLimit limits reports to rate per second globally. Defaults to 10/s with a burst of 20.
+func WithRateLimit(rate float64, burst int) Option {
+	return func(o *options) {
+		o.rate = rate
+		o.burst = burst
+	}
+}
+
+// WithBuffer sets the size of the send queue; reports are dropped when it is full. Defaults to 256.
+func WithBuffer(size int) Option {
+	return func(o *options) {
+		o.buffer = size
+	}
+}
+
+// WithSendTimeout sets the timeout of each send. Defaults to 5s.
+func WithSendTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+type fingerprintState struct {
+	last       time.Time
+	suppressed int
+}
+
+// DefaultReporter deduplicates, rate limits and sends reports asynchronously.
+type DefaultReporter struct {
+	opts    options
+	host    string
+	limiter *bucket.Bucket
+	queue   chan *Report
+	done    chan struct{}
+	once    sync.Once
+
+	mu     sync.Mutex
+	closed bool
+	seen   map[string]*fingerprintState
+	now    func() time.Time
+}
+
+var _ Reporter = (*DefaultReporter)(nil)
+
+// NewReporter returns a reporter and starts its send goroutine. Call Close on exit.
+func NewReporter(opts ...Option) *DefaultReporter {
+	o := options{
+		interval: time.Minute,
+		rate:     10,
+		burst:    20,
+		buffer:   256,
+		timeout:  5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	host, _ := os.Hostname()
+	r := &DefaultReporter{
+		opts:    o,
+		host:    host,
+		limiter: bucket.New(o.rate, o.burst),
+		queue:   make(chan *Report, o.buffer),
+		done:    make(chan struct{}),
+		seen:    make(map[string]*fingerprintState),
+		now:     time.Now,
+	}
+	go r.run()
+	return r
+}
+
+// Report fills in missing fields, deduplicates and rate limits rep, then queues it. It never blocks.
+func (r *DefaultReporter) Report(ctx context.Context, rep *Report) {
+	if rep.Time.IsZero() {
+		rep.Time = r.now()
+	}
+	if rep.Fingerprint == "" {
+		rep.Fingerprint = Fingerprint(rep)
+	}
+	if rep.TraceID == "" {
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			rep.TraceID = sc.TraceID().String()
+		}
+	}
+	if rep.Service == "" {
+		rep.Service = r.opts.service
+	}
+	if rep.Version == "" {
+		rep.Version = r.opts.version
+	}
+	rep.Host = r.host
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed || !r.dedup(rep) {
+		return
+	}
+	select {
+	case r.queue <- rep:
+	default:
+		log.Warnf("error report queue is full, drop report: %s", rep.Fingerprint)
+	}
+}
+
+// dedup reports a fingerprint at most once per interval; rate limited reports do not count as
+// reported. r.mu must be held.
+func (r *DefaultReporter) dedup(rep *Report) bool {
+	st, ok := r.seen[rep.Fingerprint]
+	if ok && rep.Time.Sub(st.last) < r.opts.interval {
+		st.suppressed++
+		return false
+	}
+	if _, err := r.limiter.Allow(); err != nil {
+		if ok {
+			st.suppressed++
+		}
+		return false
+	}
+	if !ok {
+		r.seen[rep.Fingerprint] = &fingerprintState{last: rep.Time}
+		rep.FirstSeen = true
+		rep.Count = 1
+		return true
+	}
+	rep.Count = st.suppressed + 1
+	st.last = rep.Time
+	st.suppressed = 0
+	return true
+}
+
+func (r *DefaultReporter) run() {
+	defer close(r.done)
+	for rep := range r.queue {
+		for _, sink := range r.opts.sinks {
+			ctx, cancel := context.WithTimeout(context.Background(), r.opts.timeout)
+			if err := sink.Send(ctx, rep); err != nil {
+				log.Errorf("send error report %s failed: %v", rep.Fingerprint, err)
+			}
+			cancel()
+		}
+	}
+}
+
+// Close stops accepting reports and waits for queued reports to be sent. It can be used as a
+// lori.AfterStop hook.
+func (r *DefaultReporter) Close(ctx context.Context) error {
+	r.once.Do(func() {
+		r.mu.Lock()
+		r.closed = true
+		close(r.queue)
+		r.mu.Unlock()
+	})
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Fingerprint hashes the kind, code, operation and the function names in the stack (without lines
+// or arguments). Variables in the message, such as indexes, addresses and ids, are ignored.
+func Fingerprint(rep *Report) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s|%d|%s|", rep.Kind, rep.Code, rep.Operation)
+	for _, fn := range stackFuncs(rep.Stack) {
+		fmt.Fprintln(h, fn)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// stackFuncs extracts the function names from a stack in debug.Stack() format.
+//
+//	goroutine 1 [running]:
+//	main.main()
+//		/xxx/main.go:12 +0x1d
+func stackFuncs(stack string) []string {
+	var funcs []string
+	sc := bufio.NewScanner(strings.NewReader(stack))
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "goroutine ") {
+			continue
+		}
+		if i := strings.LastIndex(line, "("); i > 0 {
+			line = line[:i]
+		}
+		funcs = append(funcs, line)
+	}
+	return funcs
+}
+
+type stackTracer interface {
+	StackTrace() errors.StackTrace
+}
+
+// errorStack formats the stack of the innermost error in the chain that has one, in the same
+// format as debug.Stack() so that stackFuncs can parse it.
+func errorStack(err error) string {
+	var st errors.StackTrace
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if t, ok := e.(stackTracer); ok {
+			st = t.StackTrace()
+		}
+	}
+	var b strings.Builder
+	for _, f := range st {
+		// %+s is "function\n\tfile"
+		name, file, _ := strings.Cut(fmt.Sprintf("%+s", f), "\n\t")
+		fmt.Fprintf(&b, "%s(...)\n\t%s:%d\n", name, file, f)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// defaultReporter drops reports until SetDefault is called.
+var (
+	defaultReporter Reporter = nopReporter{}
+	defaultMux               = &sync.RWMutex{}
+)
+
+type nopReporter struct{}
+
+func (nopReporter) Report(context.Context, *Report) {}
+
+// SetDefault sets the reporter used by Default, e.g. by gin Recovery and the gRPC crash interceptor.
+// nil disables reporting.
+func SetDefault(r Reporter) {
+	if r == nil {
+		r = nopReporter{}
+	}
+	defaultMux.Lock()
+	defer defaultMux.Unlock()
+	defaultReporter = r
+}
+
+// Default returns the default reporter.
+func Default() Reporter {
+	defaultMux.RLock()
+	defer defaultMux.RUnlock()
+	return defaultReporter
+}
+
+// Panic reports a recovered panic; stack is the output of debug.Stack().
+func Panic(ctx context.Context, recovered interface{}, stack []byte, operation string, header map[string][]string) {
+	Default().Report(ctx, &Report{
+		Kind:      KindPanic,
+		Message:   fmt.Sprintf("%v", recovered),
+		Stack:     string(bytes.TrimSpace(stack)),
+		Operation: operation,
+		Headers:   Headers(header),
+	})
+}
+
+// Error reports err. The code comes from lori errors, Message is the full error chain with callers
+// and Stack is the stack recorded where the innermost error of the chain was created, so errors from
+// different call sites get different fingerprints.
+func Error(ctx context.Context, err error, operation string, header map[string][]string) {
+	Default().Report(ctx, &Report{
+		Kind:      KindError,
+		Message:   fmt.Sprintf("%+v", err),
+		Code:      errors.ParseCoder(err).Code(),
+		Stack:     errorStack(err),
+		Operation: operation,
+		Headers:   Headers(header),
+	})
+}
@@ -0,0 +1,165 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cr-mao/lori/errors"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	reports []*Report
+}
+
+func (s *memorySink) Send(_ context.Context, r *Report) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports = append(s.reports, r)
+	return nil
+}
+
+const panicStack = `goroutine 7 [running]:
+main.handler(0xc000010000)
+	/app/main.go:12 +0x1d
+main.main()
+	/app/main.go:30 +0x25`
+
+func TestReporterDedup(t *testing.T) {
+	sink := &memorySink{}
+	r := NewReporter(WithSinks(sink), WithInterval(time.Minute), WithVersion("v1.2.0"))
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		r.Report(context.Background(), &Report{Kind: KindPanic, Message: "index out of range [" + string(rune('0'+i)) + "]", Stack: panicStack})
+	}
+	now = now.Add(2 * time.Minute)
+	r.Report(context.Background(), &Report{Kind: KindPanic, Message: "index out of range [9]", Stack: panicStack})
+	r.Report(context.Background(), &Report{Kind: KindPanic, Message: "nil map", Stack: panicStack, Operation: "/user.v1.User/Get"})
+	if err := r.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.reports) != 3 {
+		t.Fatalf("expect 3 reports, got %d", len(sink.reports))
+	}
+	first, second, other := sink.reports[0], sink.reports[1], sink.reports[2]
+	if !first.FirstSeen || first.Count != 1 || first.Version != "v1.2.0" {
+		t.Errorf("unexpected first report %+v", first)
+	}
+	if second.FirstSeen || second.Count != 3 || second.Fingerprint != first.Fingerprint {
+		t.Errorf("unexpected second report %+v", second)
+	}
+	if !other.FirstSeen || other.Fingerprint == first.Fingerprint {
+		t.Errorf("different operation should have a new fingerprint %+v", other)
+	}
+}
+
+func TestReporterRateLimit(t *testing.T) {
+	sink := &memorySink{}
+	r := NewReporter(WithSinks(sink), WithRateLimit(0.001, 2))
+	for _, op := range []string{"a", "b", "c", "d"} {
+		r.Report(context.Background(), &Report{Kind: KindError, Operation: op})
+	}
+	_ = r.Close(context.Background())
+	if len(sink.reports) != 2 {
+		t.Errorf("expect 2 reports, got %d", len(sink.reports))
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	h := Headers(http.Header{
+		"Authorization":  {"Bearer xxx"},
+		"X-Access-Token": {"xxx"},
+		"Cookie":         {"sid=1"},
+		"User-Agent":     {"curl"},
+	})
+	if h["Authorization"] != redacted || h["X-Access-Token"] != redacted || h["Cookie"] != redacted {
+		t.Errorf("secret headers should be redacted: %v", h)
+	}
+	if h["User-Agent"] != "curl" {
+		t.Errorf("unexpected headers %v", h)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.log")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if err = sink.Send(context.Background(), &Report{Kind: KindPanic, Fingerprint: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"fingerprint":"abc"`) || !strings.HasSuffix(string(data), "\n") {
+		t.Errorf("unexpected file content %s", data)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var got Report
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(srv.URL, WithWebhookHeader("X-Token", "secret"))
+	if err := sink.Send(context.Background(), &Report{Kind: KindError, Code: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Kind != KindError || got.Code != 1 {
+		t.Errorf("unexpected report %+v", got)
+	}
+	if err := NewWebhookSink(srv.URL).Send(context.Background(), &Report{}); err == nil {
+		t.Error("expect error for non-2xx response")
+	}
+}
+
+type recordReporter struct {
+	reports []*Report
+}
+
+func (r *recordReporter) Report(_ context.Context, rep *Report) {
+	r.reports = append(r.reports, rep)
+}
+
+func queryUser() error { return errors.WithCode(1, "query user failed") }
+
+func queryOrder() error { return errors.WithCode(1, "query order failed") }
+
+func TestErrorStack(t *testing.T) {
+	rec := &recordReporter{}
+	SetDefault(rec)
+	defer SetDefault(nil)
+
+	for i := 0; i < 2; i++ {
+		Error(context.Background(), errors.Wrap(queryUser(), "get user"), "/v1/user/:id", nil)
+	}
+	Error(context.Background(), queryOrder(), "/v1/user/:id", nil)
+
+	user, again, order := rec.reports[0], rec.reports[1], rec.reports[2]
+	if !strings.Contains(user.Stack, "report.queryUser(...)") || !strings.Contains(user.Stack, "report_test.go:") {
+		t.Errorf("expect stack of the innermost error, got %q", user.Stack)
+	}
+	if Fingerprint(user) != Fingerprint(again) {
+		t.Error("expect same fingerprint for errors from the same call site")
+	}
+	if Fingerprint(user) == Fingerprint(order) {
+		t.Error("expect different fingerprints for errors from different call sites")
+	}
+}
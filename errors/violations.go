@@ -0,0 +1,42 @@
+package errors
+
+// FieldViolation describes an invalid request field. Field is the field path, e.g. profile.name or items[0].id.
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// WithViolations attaches field violations to err. They are encoded as a BadRequest in the gRPC status
+// details, and as the fields list of the json body over http.
+// If err is a withCode, a copy with the violations appended is returned; otherwise err is wrapped
+// in a withCode with the unknown code.
+func WithViolations(err error, violations ...FieldViolation) error {
+	if err == nil {
+		return nil
+	}
+	w, ok := err.(*withCode)
+	if !ok {
+		return &withCode{
+			err:        err,
+			code:       unknownCoder.Code(),
+			cause:      err,
+			stack:      callers(),
+			violations: violations,
+		}
+	}
+	c := *w
+	c.violations = make([]FieldViolation, 0, len(w.violations)+len(violations))
+	c.violations = append(c.violations, w.violations...)
+	c.violations = append(c.violations, violations...)
+	return &c
+}
+
+// Violations returns the field violations of the first withCode in the chain that has any.
+func Violations(err error) []FieldViolation {
+	for _, e := range list(err) {
+		if w, ok := e.(*withCode); ok && len(w.violations) > 0 {
+			return w.violations
+		}
+	}
+	return nil
+}
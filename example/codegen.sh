@@ -2,7 +2,16 @@ find proto -type f -name "*.pb.go" -delete
 
 # https://developers.google.com/protocol-buffers/docs/reference/go-generated#package
 # 默认就是import 模式
-protoc --go_out=.  --go_opt=paths=import  \
+# google.api.http 注解生成 gin 路由, google/api/annotations.proto 在 ../third_party
+protoc --proto_path=. --proto_path=../third_party \
+    --go_out=.  --go_opt=paths=import  \
     --go-grpc_out=.  --go-grpc_opt=paths=import \
+    --lori-http_out=.  --lori-http_opt=paths=import \
     proto/helloworld.proto
 
+# 错误码 enum, errors/errors.proto 在上一级目录
+protoc --proto_path=. --proto_path=.. \
+    --go_out=.  --go_opt=paths=import  \
+    --lori-errors_out=.  --lori-errors_opt=paths=import \
+    proto/error_reason.proto
+
@@ -0,0 +1,37 @@
+package example
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/cr-mao/lori/example/proto"
+	lorihttp "github.com/cr-mao/lori/transport/http"
+)
+
+// grpc 的实现通过 protoc-gen-lori-http 生成的路由同时提供 http 接口
+func TestGreeterHTTPServer(t *testing.T) {
+	srv := lorihttp.NewServer(lorihttp.WithMode(gin.TestMode))
+	proto.RegisterGreeterHTTPServer(srv, &HelloWorldServer{})
+
+	tests := []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/v1/greeter/lori", ""},
+		{http.MethodPost, "/v1/greeter/say_hello", `{"name":"lori"}`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		var reply proto.HelloResponse
+		if w.Code != http.StatusOK || protojson.Unmarshal(w.Body.Bytes(), &reply) != nil || reply.Message != "hello lori" {
+			t.Errorf("%s %s: unexpected response %d %s", tt.method, tt.path, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.32.0
+// 	protoc        v3.21.12
+// source: proto/error_reason.proto
+
+package proto
+
+import (
+	_ "github.com/cr-mao/lori/errors"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ErrorReason int32
+
+const (
+	ErrorReason_ERROR_REASON_UNSPECIFIED ErrorReason = 0
+	// greeter not found
+	ErrorReason_GREETER_NOT_FOUND ErrorReason = 120001
+	// greeter name is required
+	ErrorReason_GREETER_NAME_REQUIRED ErrorReason = 120002
+	// greeter internal error
+	ErrorReason_GREETER_INTERNAL ErrorReason = 120003
+)
+
+// Enum value maps for ErrorReason.
+var (
+	ErrorReason_name = map[int32]string{
+		0:      "ERROR_REASON_UNSPECIFIED",
+		120001: "GREETER_NOT_FOUND",
+		120002: "GREETER_NAME_REQUIRED",
+		120003: "GREETER_INTERNAL",
+	}
+	ErrorReason_value = map[string]int32{
+		"ERROR_REASON_UNSPECIFIED": 0,
+		"GREETER_NOT_FOUND":        120001,
+		"GREETER_NAME_REQUIRED":    120002,
+		"GREETER_INTERNAL":         120003,
+	}
+)
+
+func (x ErrorReason) Enum() *ErrorReason {
+	p := new(ErrorReason)
+	*p = x
+	return p
+}
+
+func (x ErrorReason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ErrorReason) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_error_reason_proto_enumTypes[0].Descriptor()
+}
+
+func (ErrorReason) Type() protoreflect.EnumType {
+	return &file_proto_error_reason_proto_enumTypes[0]
+}
+
+func (x ErrorReason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ErrorReason.Descriptor instead.
+func (ErrorReason) EnumDescriptor() ([]byte, []int) {
+	return file_proto_error_reason_proto_rawDescGZIP(), []int{0}
+}
+
+var File_proto_error_reason_proto protoreflect.FileDescriptor
+
+var file_proto_error_reason_proto_rawDesc = []byte{
+	0x0a, 0x18, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x72, 0x65,
+	0x61, 0x73, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x12, 0x6c, 0x6f, 0x72, 0x69,
+	0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x13,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x2f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2a, 0x8b, 0x01, 0x0a, 0x0b, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x65, 0x61,
+	0x73, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x18, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x5f, 0x52, 0x45, 0x41,
+	0x53, 0x4f, 0x4e, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10,
+	0x00, 0x12, 0x1d, 0x0a, 0x11, 0x47, 0x52, 0x45, 0x45, 0x54, 0x45, 0x52, 0x5f, 0x4e, 0x4f, 0x54,
+	0x5f, 0x46, 0x4f, 0x55, 0x4e, 0x44, 0x10, 0xc1, 0xa9, 0x07, 0x1a, 0x04, 0xa8, 0x45, 0x94, 0x03,
+	0x12, 0x21, 0x0a, 0x15, 0x47, 0x52, 0x45, 0x45, 0x54, 0x45, 0x52, 0x5f, 0x4e, 0x41, 0x4d, 0x45,
+	0x5f, 0x52, 0x45, 0x51, 0x55, 0x49, 0x52, 0x45, 0x44, 0x10, 0xc2, 0xa9, 0x07, 0x1a, 0x04, 0xa8,
+	0x45, 0x90, 0x03, 0x12, 0x16, 0x0a, 0x10, 0x47, 0x52, 0x45, 0x45, 0x54, 0x45, 0x52, 0x5f, 0x49,
+	0x4e, 0x54, 0x45, 0x52, 0x4e, 0x41, 0x4c, 0x10, 0xc3, 0xa9, 0x07, 0x1a, 0x04, 0xa0, 0x45, 0xf4,
+	0x03, 0x42, 0x09, 0x5a, 0x07, 0x2e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_proto_error_reason_proto_rawDescOnce sync.Once
+	file_proto_error_reason_proto_rawDescData = file_proto_error_reason_proto_rawDesc
+)
+
+func file_proto_error_reason_proto_rawDescGZIP() []byte {
+	file_proto_error_reason_proto_rawDescOnce.Do(func() {
+		file_proto_error_reason_proto_rawDescData = protoimpl.X.CompressGZIP(file_proto_error_reason_proto_rawDescData)
+	})
+	return file_proto_error_reason_proto_rawDescData
+}
+
+var file_proto_error_reason_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_error_reason_proto_goTypes = []interface{}{
+	(ErrorReason)(0), // 0: lori.example.proto.ErrorReason
+}
+var file_proto_error_reason_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_proto_error_reason_proto_init() }
+func file_proto_error_reason_proto_init() {
+	if File_proto_error_reason_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_proto_error_reason_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   0,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_proto_error_reason_proto_goTypes,
+		DependencyIndexes: file_proto_error_reason_proto_depIdxs,
+		EnumInfos:         file_proto_error_reason_proto_enumTypes,
+	}.Build()
+	File_proto_error_reason_proto = out.File
+	file_proto_error_reason_proto_rawDesc = nil
+	file_proto_error_reason_proto_goTypes = nil
+	file_proto_error_reason_proto_depIdxs = nil
+}
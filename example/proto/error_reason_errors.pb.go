@@ -0,0 +1,46 @@
+// Code generated by protoc-gen-lori-errors. DO NOT EDIT.
+// versions:
+// - protoc-gen-lori-errors v0.1.0
+// source: proto/error_reason.proto
+
+package proto
+
+import (
+	errors "github.com/cr-mao/lori/errors"
+)
+
+func init() {
+	errors.MustRegister(errors.NewReasonCoder(int(ErrorReason_GREETER_NOT_FOUND), 404, "GREETER_NOT_FOUND", "greeter not found", ""))
+	errors.MustRegister(errors.NewReasonCoder(int(ErrorReason_GREETER_NAME_REQUIRED), 400, "GREETER_NAME_REQUIRED", "greeter name is required", ""))
+	errors.MustRegister(errors.NewReasonCoder(int(ErrorReason_GREETER_INTERNAL), 500, "GREETER_INTERNAL", "greeter internal error", ""))
+}
+
+// IsGreeterNotFound greeter not found
+func IsGreeterNotFound(err error) bool {
+	return errors.IsCode(err, int(ErrorReason_GREETER_NOT_FOUND))
+}
+
+// ErrorGreeterNotFound greeter not found
+func ErrorGreeterNotFound(format string, args ...interface{}) error {
+	return errors.WithCode(int(ErrorReason_GREETER_NOT_FOUND), format, args...)
+}
+
+// IsGreeterNameRequired greeter name is required
+func IsGreeterNameRequired(err error) bool {
+	return errors.IsCode(err, int(ErrorReason_GREETER_NAME_REQUIRED))
+}
+
+// ErrorGreeterNameRequired greeter name is required
+func ErrorGreeterNameRequired(format string, args ...interface{}) error {
+	return errors.WithCode(int(ErrorReason_GREETER_NAME_REQUIRED), format, args...)
+}
+
+// IsGreeterInternal greeter internal error
+func IsGreeterInternal(err error) bool {
+	return errors.IsCode(err, int(ErrorReason_GREETER_INTERNAL))
+}
+
+// ErrorGreeterInternal greeter internal error
+func ErrorGreeterInternal(format string, args ...interface{}) error {
+	return errors.WithCode(int(ErrorReason_GREETER_INTERNAL), format, args...)
+}
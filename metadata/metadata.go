@@ -0,0 +1,192 @@
+package metadata
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cr-mao/lori/transport"
+)
+
+const (
+	// DefaultPrefix 需要透传的 key 前缀, 服务端只提取这个前缀的请求头
+	DefaultPrefix = "x-md-"
+	// GlobalPrefix 全局 key 前缀, 会跨多个服务一直往下传递, 比如 x-md-global-tenant-id
+	GlobalPrefix = "x-md-global-"
+	// LocalPrefix 本地 key 前缀, 只往下传一跳
+	LocalPrefix = "x-md-local-"
+)
+
+// Metadata 跨服务传递的 key-value, key 统一为小写
+type Metadata map[string][]string
+
+// New 创建 Metadata
+func New(mds ...map[string][]string) Metadata {
+	md := Metadata{}
+	for _, m := range mds {
+		for k, vList := range m {
+			for _, v := range vList {
+				md.Add(k, v)
+			}
+		}
+	}
+	return md
+}
+
+// Add 追加 value
+func (m Metadata) Add(key, value string) {
+	if len(key) == 0 {
+		return
+	}
+	key = strings.ToLower(key)
+	m[key] = append(m[key], value)
+}
+
+// Get 返回 key 的第一个 value
+func (m Metadata) Get(key string) string {
+	v := m[strings.ToLower(key)]
+	if len(v) == 0 {
+		return ""
+	}
+	return v[0]
+}
+
+// Set 设置 key 的 value, 会覆盖原有的值
+func (m Metadata) Set(key string, value string) {
+	if key == "" || value == "" {
+		return
+	}
+	m[strings.ToLower(key)] = []string{value}
+}
+
+// Values 返回 key 的所有 value
+func (m Metadata) Values(key string) []string {
+	return m[strings.ToLower(key)]
+}
+
+// Range 遍历, f 返回 false 时停止
+func (m Metadata) Range(f func(k string, v []string) bool) {
+	for k, v := range m {
+		if !f(k, v) {
+			break
+		}
+	}
+}
+
+// Clone 深拷贝
+func (m Metadata) Clone() Metadata {
+	md := make(Metadata, len(m))
+	for k, v := range m {
+		md[k] = append([]string(nil), v...)
+	}
+	return md
+}
+
+type (
+	serverMetadataKey struct{}
+	clientMetadataKey struct{}
+)
+
+// NewServerContext 服务端收到的 metadata
+func NewServerContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, serverMetadataKey{}, md)
+}
+
+// FromServerContext 获取服务端收到的 metadata
+func FromServerContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(serverMetadataKey{}).(Metadata)
+	return md, ok
+}
+
+// NewClientContext 客户端要发出的 metadata, 只传一跳
+func NewClientContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, clientMetadataKey{}, md)
+}
+
+// FromClientContext 获取客户端要发出的 metadata
+func FromClientContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(clientMetadataKey{}).(Metadata)
+	return md, ok
+}
+
+// AppendToClientContext 往客户端 metadata 中追加 key-value 对
+func AppendToClientContext(ctx context.Context, kv ...string) context.Context {
+	if len(kv)%2 == 1 {
+		panic("metadata: AppendToClientContext got an odd number of input pairs for metadata")
+	}
+	md, _ := FromClientContext(ctx)
+	md = md.Clone()
+	for i := 0; i < len(kv); i += 2 {
+		md.Set(kv[i], kv[i+1])
+	}
+	return NewClientContext(ctx, md)
+}
+
+// MergeToClientContext 合并到客户端 metadata
+func MergeToClientContext(ctx context.Context, cmd Metadata) context.Context {
+	md, _ := FromClientContext(ctx)
+	md = md.Clone()
+	for k, v := range cmd {
+		md[strings.ToLower(k)] = v
+	}
+	return NewClientContext(ctx, md)
+}
+
+// Extract 服务端从请求头中提取指定前缀的 key, 默认前缀 DefaultPrefix
+func Extract(ctx context.Context, header transport.Header, prefixes ...string) context.Context {
+	if len(prefixes) == 0 {
+		prefixes = []string{DefaultPrefix}
+	}
+	md := Metadata{}
+	for _, k := range header.Keys() {
+		if hasPrefix(k, prefixes) {
+			for _, v := range header.Values(k) {
+				md.Add(k, v)
+			}
+		}
+	}
+	return NewServerContext(ctx, md)
+}
+
+// Inject 客户端把 metadata 写入请求头:
+// 1. 客户端 context 中的 metadata, 只传一跳
+// 2. 服务端收到的全局 metadata (GlobalPrefix), 继续往下传
+func Inject(ctx context.Context, header transport.Header, globalPrefixes ...string) {
+	if len(globalPrefixes) == 0 {
+		globalPrefixes = []string{GlobalPrefix}
+	}
+	if md, ok := FromServerContext(ctx); ok {
+		for k, v := range md {
+			if !hasPrefix(k, globalPrefixes) {
+				continue
+			}
+			for i, vv := range v {
+				if i == 0 {
+					header.Set(k, vv)
+				} else {
+					header.Add(k, vv)
+				}
+			}
+		}
+	}
+	if md, ok := FromClientContext(ctx); ok {
+		for k, v := range md {
+			for i, vv := range v {
+				if i == 0 {
+					header.Set(k, vv)
+				} else {
+					header.Add(k, vv)
+				}
+			}
+		}
+	}
+}
+
+func hasPrefix(key string, prefixes []string) bool {
+	key = strings.ToLower(key)
+	for _, p := range prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
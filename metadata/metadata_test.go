@@ -0,0 +1,74 @@
+package metadata
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type header map[string][]string
+
+func (h header) Get(key string) string {
+	if v := h[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+func (h header) Set(key, value string)      { h[key] = []string{value} }
+func (h header) Add(key, value string)      { h[key] = append(h[key], value) }
+func (h header) Values(key string) []string { return h[key] }
+func (h header) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestMetadata(t *testing.T) {
+	md := New(map[string][]string{"X-MD-Tenant": {"t1"}})
+	if v := md.Get("x-md-tenant"); v != "t1" {
+		t.Errorf("expect %v, got %v", "t1", v)
+	}
+	md.Add("x-md-tenant", "t2")
+	if v := md.Values("X-Md-Tenant"); !reflect.DeepEqual(v, []string{"t1", "t2"}) {
+		t.Errorf("expect %v, got %v", []string{"t1", "t2"}, v)
+	}
+	clone := md.Clone()
+	clone.Set("x-md-tenant", "t3")
+	if md.Get("x-md-tenant") != "t1" {
+		t.Errorf("expect clone not affect origin")
+	}
+}
+
+func TestExtractAndInject(t *testing.T) {
+	in := header{
+		"x-md-global-tenant-id": {"1001"},
+		"x-md-local-caller":     {"gateway"},
+		"authorization":         {"token"},
+	}
+	ctx := Extract(context.Background(), in)
+	md, ok := FromServerContext(ctx)
+	if !ok {
+		t.Fatal("expect server metadata")
+	}
+	if md.Get("authorization") != "" {
+		t.Errorf("expect header without prefix not extracted")
+	}
+	if md.Get("x-md-local-caller") != "gateway" {
+		t.Errorf("expect %v, got %v", "gateway", md.Get("x-md-local-caller"))
+	}
+
+	ctx = AppendToClientContext(ctx, "x-md-local-user-id", "42")
+	out := header{}
+	Inject(ctx, out)
+	expect := header{
+		"x-md-global-tenant-id": {"1001"},
+		"x-md-local-user-id":    {"42"},
+	}
+	if !reflect.DeepEqual(out, expect) {
+		t.Errorf("expect %v, got %v", expect, out)
+	}
+}
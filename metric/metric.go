@@ -1,12 +1,15 @@
 package metric
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"google.golang.org/grpc"
 )
 
 type GrpcMetric interface {
-	GrpcMetricInterceptors() []grpc.UnaryServerInterceptor //grpc 中间件
+	GrpcMetricInterceptors() []grpc.UnaryServerInterceptor        //grpc 中间件
+	GrpcMetricStreamInterceptors() []grpc.StreamServerInterceptor //grpc stream 中间件
 }
 
 type GinMetric interface {
@@ -15,5 +18,10 @@ type GinMetric interface {
 }
 
 type GrpcClientMetric interface {
-	GrpcClientMetricInterceptors() []grpc.UnaryClientInterceptor // grpc client 中间件
+	GrpcClientMetricInterceptors() []grpc.UnaryClientInterceptor        // grpc client 中间件
+	GrpcClientMetricStreamInterceptors() []grpc.StreamClientInterceptor // grpc client stream 中间件
+}
+
+type HttpClientMetric interface {
+	HttpClientMetricRoundTripper(next http.RoundTripper) http.RoundTripper // http client 中间件
 }
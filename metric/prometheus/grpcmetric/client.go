@@ -0,0 +1,29 @@
+package grpcmetric
+
+import (
+	"github.com/cr-mao/lori/metric/prometheus"
+)
+
+// NewHedgingCounter 对冲请求计数, 配合 grpc.WithHedgingCounter 使用
+// labels: method, result(sent: 发出了对冲请求, won: 对冲请求先返回)
+func NewHedgingCounter(serverName string) prometheus.CounterVec {
+	return prometheus.NewCounterVec(&prometheus.CounterVecOpts{
+		Namespace: serverName + "_grpc_client",
+		Subsystem: "requests",
+		Name:      "grpc_client_hedging_total",
+		Help:      "rpc client hedged requests count.",
+		Labels:    []string{"method", "result"},
+	})
+}
+
+// NewClientConnStateGauge grpc 客户端连接状态, 配合 grpc.WithConnStateGauge 使用
+// labels: target, state(IDLE|CONNECTING|READY|TRANSIENT_FAILURE|SHUTDOWN)
+func NewClientConnStateGauge(serverName string) prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(&prometheus.GaugeVecOpts{
+		Namespace: serverName + "_grpc_client",
+		Subsystem: "conns",
+		Name:      "grpc_client_conn_state",
+		Help:      "rpc client connections by state.",
+		Labels:    []string{"target", "state"},
+	})
+}
@@ -0,0 +1,123 @@
+package grpcmetric
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/cr-mao/lori/metric"
+	"github.com/cr-mao/lori/metric/prometheus"
+)
+
+type ClientPromInstance struct {
+	metricClientReqDur    prometheus.HistogramVec
+	metricClientStreamDur prometheus.HistogramVec // 流的生命周期
+	metricClientStreamMsg prometheus.CounterVec   // 流中收发的消息数
+	serverName            string
+}
+
+var _ metric.GrpcClientMetric = (*ClientPromInstance)(nil)
+
+func NewClientMetricInstance(serverName string) metric.GrpcClientMetric {
+	metricClientReqDur := prometheus.NewHistogramVec(&prometheus.HistogramVecOpts{
+		Namespace: serverName + "_grpc_client",
+		Subsystem: "requests",
+		Name:      "grpc_client_duration_ms",
+		Help:      "rpc client requests duration(ms).",
+		Labels:    []string{"method"},
+		Buckets:   []float64{30, 50, 100, 250, 500, 1000, 2000},
+	})
+	metricClientStreamDur := prometheus.NewHistogramVec(&prometheus.HistogramVecOpts{
+		Namespace: serverName + "_grpc_client",
+		Subsystem: "streams",
+		Name:      "grpc_client_stream_duration_ms",
+		Help:      "rpc client streams lifetime(ms).",
+		Labels:    []string{"method"},
+		Buckets:   []float64{100, 1000, 10000, 60000, 300000, 1800000},
+	})
+	metricClientStreamMsg := prometheus.NewCounterVec(&prometheus.CounterVecOpts{
+		Namespace: serverName + "_grpc_client",
+		Subsystem: "streams",
+		Name:      "grpc_client_stream_msg_total",
+		Help:      "rpc client stream messages count.",
+		Labels:    []string{"method", "direction"},
+	})
+	return &ClientPromInstance{
+		serverName:            serverName,
+		metricClientReqDur:    metricClientReqDur,
+		metricClientStreamDur: metricClientStreamDur,
+		metricClientStreamMsg: metricClientStreamMsg,
+	}
+}
+
+func (p *ClientPromInstance) GrpcClientMetricInterceptors() []grpc.UnaryClientInterceptor {
+	return []grpc.UnaryClientInterceptor{p.clientUnaryPrometheusInterceptor}
+}
+
+func (p *ClientPromInstance) GrpcClientMetricStreamInterceptors() []grpc.StreamClientInterceptor {
+	return []grpc.StreamClientInterceptor{p.clientStreamPrometheusInterceptor}
+}
+
+// 每个方法耗时的中间件
+func (p *ClientPromInstance) clientUnaryPrometheusInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	startTime := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	p.metricClientReqDur.Observe(int64(time.Since(startTime)/time.Millisecond), method)
+	return err
+}
+
+// 流的生命周期和收发消息数, 生命周期在收到 io.EOF 或错误时结束
+func (p *ClientPromInstance) clientStreamPrometheusInterceptor(ctx context.Context, desc *grpc.StreamDesc,
+	cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	startTime := time.Now()
+	s, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		p.metricClientStreamDur.Observe(int64(time.Since(startTime)/time.Millisecond), method)
+		return s, err
+	}
+	return &clientStream{
+		ClientStream: s,
+		desc:         desc,
+		method:       method,
+		counter:      p.metricClientStreamMsg,
+		finish: func() {
+			p.metricClientStreamDur.Observe(int64(time.Since(startTime)/time.Millisecond), method)
+		},
+	}, nil
+}
+
+// clientStream 统计收发的消息数
+type clientStream struct {
+	grpc.ClientStream
+	desc    *grpc.StreamDesc
+	method  string
+	counter prometheus.CounterVec
+	finish  func()
+	once    sync.Once
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.counter.Inc(s.method, "sent")
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		s.counter.Inc(s.method, "received")
+		// 非服务端流只会收到一条消息
+		if !s.desc.ServerStreams {
+			s.once.Do(s.finish)
+		}
+		return nil
+	}
+	// io.EOF 或者出错, 流结束
+	s.once.Do(s.finish)
+	return err
+}
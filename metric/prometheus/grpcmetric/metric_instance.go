@@ -11,8 +11,10 @@ import (
 )
 
 type PromInstance struct {
-	metricServerReqDur prometheus.HistogramVec
-	serverName         string
+	metricServerReqDur    prometheus.HistogramVec
+	metricServerStreamDur prometheus.HistogramVec // 流的生命周期
+	metricServerStreamMsg prometheus.CounterVec   // 流中收发的消息数
+	serverName            string
 }
 
 var _ metric.GrpcMetric = (*PromInstance)(nil)
@@ -26,9 +28,26 @@ func NewMetricInstance(serverName string) metric.GrpcMetric {
 		Labels:    []string{"method"},
 		Buckets:   []float64{30, 50, 100, 250, 500, 1000, 2000},
 	})
+	metricServerStreamDur := prometheus.NewHistogramVec(&prometheus.HistogramVecOpts{
+		Namespace: serverName + "_grpc_server",
+		Subsystem: "streams",
+		Name:      "grpc_server_stream_duration_ms",
+		Help:      "rpc server streams lifetime(ms).",
+		Labels:    []string{"method"},
+		Buckets:   []float64{100, 1000, 10000, 60000, 300000, 1800000},
+	})
+	metricServerStreamMsg := prometheus.NewCounterVec(&prometheus.CounterVecOpts{
+		Namespace: serverName + "_grpc_server",
+		Subsystem: "streams",
+		Name:      "grpc_server_stream_msg_total",
+		Help:      "rpc server stream messages count.",
+		Labels:    []string{"method", "direction"},
+	})
 	return &PromInstance{
-		serverName:         serverName,
-		metricServerReqDur: metricServerReqDur,
+		serverName:            serverName,
+		metricServerReqDur:    metricServerReqDur,
+		metricServerStreamDur: metricServerStreamDur,
+		metricServerStreamMsg: metricServerStreamMsg,
 	}
 }
 
@@ -36,6 +55,10 @@ func (p *PromInstance) GrpcMetricInterceptors() []grpc.UnaryServerInterceptor {
 	return []grpc.UnaryServerInterceptor{p.serverUnaryPrometheusInterceptor}
 }
 
+func (p *PromInstance) GrpcMetricStreamInterceptors() []grpc.StreamServerInterceptor {
+	return []grpc.StreamServerInterceptor{p.serverStreamPrometheusInterceptor}
+}
+
 // 每个方法耗时的中间件
 func (p *PromInstance) serverUnaryPrometheusInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
 	handler grpc.UnaryHandler) (resp interface{}, err error) {
@@ -45,3 +68,35 @@ func (p *PromInstance) serverUnaryPrometheusInterceptor(ctx context.Context, req
 	p.metricServerReqDur.Observe(int64(time.Since(startTime)/time.Millisecond), info.FullMethod)
 	return resp, err
 }
+
+// 流的生命周期和收发消息数
+func (p *PromInstance) serverStreamPrometheusInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	startTime := time.Now()
+	err := handler(srv, &serverStream{ServerStream: ss, method: info.FullMethod, counter: p.metricServerStreamMsg})
+	p.metricServerStreamDur.Observe(int64(time.Since(startTime)/time.Millisecond), info.FullMethod)
+	return err
+}
+
+// serverStream 统计收发的消息数
+type serverStream struct {
+	grpc.ServerStream
+	method  string
+	counter prometheus.CounterVec
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.counter.Inc(s.method, "sent")
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.counter.Inc(s.method, "received")
+	}
+	return err
+}
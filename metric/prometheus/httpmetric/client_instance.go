@@ -0,0 +1,57 @@
+package httpmetric
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cr-mao/lori/metric"
+	"github.com/cr-mao/lori/metric/prometheus"
+	"github.com/cr-mao/lori/transport"
+)
+
+type ClientPromInstance struct {
+	metricClientReqDur prometheus.HistogramVec
+	serverName         string
+}
+
+var _ metric.HttpClientMetric = (*ClientPromInstance)(nil)
+
+func NewClientMetricInstance(serverName string) metric.HttpClientMetric {
+	metricClientReqDur := prometheus.NewHistogramVec(&prometheus.HistogramVecOpts{
+		Namespace: serverName + "_http_client",
+		Subsystem: "requests",
+		Name:      "http_client_duration_ms",
+		Help:      "http client requests duration(ms).",
+		Labels:    []string{"method", "operation", "code"},
+		Buckets:   []float64{30, 50, 100, 250, 500, 1000, 2000},
+	})
+	return &ClientPromInstance{
+		serverName:         serverName,
+		metricClientReqDur: metricClientReqDur,
+	}
+}
+
+// HttpClientMetricRoundTripper 每个请求的耗时, operation 取 client Transporter 的 Operation, 没有时使用请求路径
+func (p *ClientPromInstance) HttpClientMetricRoundTripper(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		startTime := time.Now()
+		resp, err := next.RoundTrip(req)
+		code := "error"
+		if err == nil {
+			code = strconv.Itoa(resp.StatusCode)
+		}
+		operation := req.URL.Path
+		if tr, ok := transport.FromClientContext(req.Context()); ok && tr.Operation() != "" {
+			operation = tr.Operation()
+		}
+		p.metricClientReqDur.Observe(int64(time.Since(startTime)/time.Millisecond), req.Method, operation, code)
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,48 @@
+// Package logging 请求日志中间件
+package logging
+
+import (
+	"context"
+	"time"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/log"
+	"github.com/cr-mao/lori/middleware"
+	"github.com/cr-mao/lori/transport"
+)
+
+// Server 每个请求打印一行日志: 传输类型, operation, 错误码, 耗时, 错误, 以及 errors.WithFields 附加的字段;
+// 出错时为 error 级别, 否则为 info 级别, logger 为 nil 时使用全局 logger
+func Server(logger log.Logger) middleware.Middleware {
+	return func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			var kind, operation string
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				kind = tr.Kind().String()
+				operation = tr.Operation()
+			}
+			startTime := time.Now()
+			reply, err := next(ctx, req)
+			level, code, reason := log.LevelInfo, 0, ""
+			if err != nil {
+				level = log.LevelError
+				code = errors.ParseCoder(err).Code()
+				reason = err.Error()
+			}
+			l := logger
+			if l == nil {
+				l = log.GetLogger()
+			}
+			keyvals := []interface{}{
+				"kind", "server",
+				"component", kind,
+				"operation", operation,
+				"code", code,
+				"reason", reason,
+				"latency", time.Since(startTime).Seconds(),
+			}
+			_ = log.WithContext(ctx, l).Log(level, append(keyvals, errors.Fields(err)...)...)
+			return reply, err
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/log"
+)
+
+func TestServerFields(t *testing.T) {
+	var buf bytes.Buffer
+	h := Server(log.NewStdLogger(&buf))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.WithFields(errors.New("db timeout"), "user_id", 42, "table", "orders")
+	})
+	if _, err := h(context.Background(), nil); err == nil {
+		t.Fatal("expect error")
+	}
+	if out := buf.String(); !strings.Contains(out, "user_id=42") || !strings.Contains(out, "table=orders") {
+		t.Errorf("expect error fields in log, got %q", out)
+	}
+}
@@ -0,0 +1,81 @@
+// Package metrics 请求耗时和请求数中间件, label 为 kind(http/grpc), operation, code(lori 错误码, 成功为0)
+//
+//	metrics.Server(
+//		metrics.WithMilliseconds(prometheus.NewHistogramVec(&prometheus.HistogramVecOpts{
+//			Namespace: "lori_server",
+//			Subsystem: "requests",
+//			Name:      "duration_ms",
+//			Labels:    []string{"kind", "operation", "code"},
+//			Buckets:   []float64{30, 50, 100, 250, 500, 1000, 2000},
+//		})),
+//		metrics.WithRequests(prometheus.NewCounterVec(&prometheus.CounterVecOpts{
+//			Namespace: "lori_server",
+//			Subsystem: "requests",
+//			Name:      "total",
+//			Labels:    []string{"kind", "operation", "code"},
+//		})),
+//	)
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/metric/prometheus"
+	"github.com/cr-mao/lori/middleware"
+	"github.com/cr-mao/lori/transport"
+)
+
+type options struct {
+	milliseconds prometheus.HistogramVec
+	requests     prometheus.CounterVec
+}
+
+// Option metrics 选项
+type Option func(*options)
+
+// WithMilliseconds 请求耗时(毫秒)
+func WithMilliseconds(h prometheus.HistogramVec) Option {
+	return func(o *options) {
+		o.milliseconds = h
+	}
+}
+
+// WithRequests 请求数
+func WithRequests(c prometheus.CounterVec) Option {
+	return func(o *options) {
+		o.requests = c
+	}
+}
+
+// Server 服务端指标中间件
+func Server(opts ...Option) middleware.Middleware {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			var kind, operation string
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				kind = tr.Kind().String()
+				operation = tr.Operation()
+			}
+			startTime := time.Now()
+			reply, err := next(ctx, req)
+			code := 0
+			if err != nil {
+				code = errors.ParseCoder(err).Code()
+			}
+			if o.milliseconds != nil {
+				o.milliseconds.Observe(int64(time.Since(startTime)/time.Millisecond), kind, operation, strconv.Itoa(code))
+			}
+			if o.requests != nil {
+				o.requests.Inc(kind, operation, strconv.Itoa(code))
+			}
+			return reply, err
+		}
+	}
+}
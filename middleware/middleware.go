@@ -0,0 +1,55 @@
+// Package middleware 与传输层无关的中间件, 基于 context.Context 和 transport.Transporter,
+// 同一条链可以同时安装到 transport/http.Server 和 transport/grpc.Server:
+//
+//	ms := []middleware.Middleware{recovery.Recovery(), tracing.Server(), logging.Server(nil)}
+//	httpSrv := http.NewServer(http.WithMiddleware(ms...))
+//	grpcSrv := grpc.NewServer(grpc.WithMiddleware(ms...))
+//
+// http 下 req 为 *http.Request, grpc unary 下为请求消息, grpc stream 下为 nil;
+// 传输层信息通过 transport.FromServerContext(ctx) 获取.
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cr-mao/lori/transport"
+)
+
+// Handler 处理请求
+type Handler func(ctx context.Context, req interface{}) (interface{}, error)
+
+// Middleware 包装 Handler
+type Middleware func(Handler) Handler
+
+// Chain 组合中间件, 第一个在最外层
+func Chain(m ...Middleware) Middleware {
+	return func(next Handler) Handler {
+		for i := len(m) - 1; i >= 0; i-- {
+			next = m[i](next)
+		}
+		return next
+	}
+}
+
+// Operation 当前请求的 operation, grpc 为 full method, http 为路由模板
+func Operation(ctx context.Context) string {
+	if tr, ok := transport.FromServerContext(ctx); ok {
+		return tr.Operation()
+	}
+	return ""
+}
+
+// Header 请求头转换成 map, 用于上报, 日志等
+func Header(ctx context.Context) map[string][]string {
+	tr, ok := transport.FromServerContext(ctx)
+	if !ok || tr.RequestHeader() == nil {
+		return nil
+	}
+	h := tr.RequestHeader()
+	header := make(map[string][]string, len(h.Keys()))
+	for _, k := range h.Keys() {
+		header[http.CanonicalHeaderKey(k)] = h.Values(k)
+	}
+	return header
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	var calls []string
+	mw := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, req interface{}) (interface{}, error) {
+				calls = append(calls, name+" before")
+				reply, err := next(ctx, req)
+				calls = append(calls, name+" after")
+				return reply, err
+			}
+		}
+	}
+	h := Chain(mw("a"), mw("b"))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls = append(calls, "handler")
+		return req, nil
+	})
+	reply, err := h(context.Background(), "lori")
+	if err != nil || reply != "lori" {
+		t.Fatalf("unexpected reply %v %v", reply, err)
+	}
+	want := []string{"a before", "b before", "handler", "b after", "a after"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expect %v, got %v", want, calls)
+	}
+}
@@ -0,0 +1,58 @@
+// Package recovery panic 恢复中间件
+package recovery
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/errors/report"
+	"github.com/cr-mao/lori/log"
+	"github.com/cr-mao/lori/middleware"
+)
+
+// ErrUnknownRequest panic 默认转换的错误码, 即 errors 包内置的 unknown coder(500)
+const ErrUnknownRequest = 1
+
+// HandlerFunc panic 转换为错误
+type HandlerFunc func(ctx context.Context, req, recovered interface{}) error
+
+type options struct {
+	handler HandlerFunc
+}
+
+// Option recovery 选项
+type Option func(*options)
+
+// WithHandler 自定义 panic 转换的错误, 默认返回 ErrUnknownRequest, 对外不暴露 panic 信息
+func WithHandler(h HandlerFunc) Option {
+	return func(o *options) {
+		o.handler = h
+	}
+}
+
+// Recovery 恢复 panic, 打印调用栈, 上报到 report.Default()
+func Recovery(opts ...Option) middleware.Middleware {
+	o := options{
+		handler: func(ctx context.Context, req, recovered interface{}) error {
+			return errors.WithCode(ErrUnknownRequest, "panic: %v", recovered)
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					stack := debug.Stack()
+					op := middleware.Operation(ctx)
+					log.Errorf("recovery operation: %s, message: %+v\n \n %s", op, r, stack)
+					report.Panic(ctx, r, stack, op, middleware.Header(ctx))
+					err = o.handler(ctx, req, r)
+				}
+			}()
+			return next(ctx, req)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package recovery
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cr-mao/lori/errors"
+)
+
+func TestRecovery(t *testing.T) {
+	h := Recovery()(func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	_, err := h(context.Background(), nil)
+	if !errors.IsCode(err, ErrUnknownRequest) || errors.ParseCoder(err).HTTPStatus() != http.StatusInternalServerError {
+		t.Fatalf("expect internal error, got %v", err)
+	}
+
+	want := errors.New("custom")
+	h = Recovery(WithHandler(func(ctx context.Context, req, recovered interface{}) error {
+		return want
+	}))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	if _, err = h(context.Background(), nil); err != want {
+		t.Errorf("expect %v, got %v", want, err)
+	}
+}
@@ -0,0 +1,83 @@
+// Package tracing opentelemetry 链路追踪中间件
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/middleware"
+	trace2 "github.com/cr-mao/lori/trace"
+	"github.com/cr-mao/lori/transport"
+)
+
+var _ propagation.TextMapCarrier = (transport.Header)(nil)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// Option tracing 选项
+type Option func(*options)
+
+// WithTracerProvider 默认使用 otel.GetTracerProvider()
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithPropagator 默认使用 otel.GetTextMapPropagator()
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(o *options) {
+		o.propagator = p
+	}
+}
+
+// Server 从请求头中提取上游的链路信息, 为每个请求创建 server span, span 名为 operation
+func Server(opts ...Option) middleware.Middleware {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return next(ctx, req)
+			}
+			tp, p := o.tracerProvider, o.propagator
+			if tp == nil {
+				tp = otel.GetTracerProvider()
+			}
+			if p == nil {
+				p = otel.GetTextMapPropagator()
+			}
+			if h := tr.RequestHeader(); h != nil {
+				ctx = p.Extract(ctx, h)
+			}
+			ctx, span := tp.Tracer(trace2.TraceName).Start(ctx, tr.Operation(),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("transport.kind", tr.Kind().String()),
+					attribute.String("transport.endpoint", tr.Endpoint()),
+				),
+			)
+			defer span.End()
+			reply, err := next(ctx, req)
+			if err != nil {
+				coder := errors.ParseCoder(err)
+				span.SetAttributes(attribute.Int("lori.error.code", coder.Code()))
+				span.RecordError(err)
+				span.SetStatus(codes.Error, coder.String())
+			}
+			return reply, err
+		}
+	}
+}
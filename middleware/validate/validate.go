@@ -0,0 +1,125 @@
+// Package validate 请求参数校验中间件, 请求实现了 Validate() error 时调用,
+// 比如 protoc-gen-validate 生成的消息
+package validate
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/middleware"
+)
+
+// ErrValidation 参数校验失败
+const ErrValidation = 100400
+
+func init() {
+	errors.Register(errors.NewReasonCoder(ErrValidation, http.StatusBadRequest, "VALIDATOR", "Invalid argument", ""))
+}
+
+type validator interface {
+	Validate() error
+}
+
+type allValidator interface {
+	ValidateAll() error
+}
+
+// protoc-gen-validate 生成的字段错误
+type fieldError interface {
+	Field() string
+	Reason() string
+	Cause() error
+}
+
+// protoc-gen-validate ValidateAll 返回的错误列表
+type multiError interface {
+	AllErrors() []error
+}
+
+// Validator 校验失败时返回 ErrValidation, 不再执行 handler
+func Validator() middleware.Middleware {
+	return func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if err := Validate(req); err != nil {
+				return nil, err
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// Validate 校验请求, 优先调用 ValidateAll() 返回所有字段的错误, 其次是 Validate();
+// 失败时返回 ErrValidation, 字段错误通过 errors.Violations 获取, 字段名使用 proto 字段名
+func Validate(req interface{}) error {
+	var err error
+	switch v := req.(type) {
+	case allValidator:
+		err = v.ValidateAll()
+	case validator:
+		err = v.Validate()
+	default:
+		return nil
+	}
+	if err == nil {
+		return nil
+	}
+	var desc protoreflect.MessageDescriptor
+	if m, ok := req.(proto.Message); ok {
+		desc = m.ProtoReflect().Descriptor()
+	}
+	return errors.WithViolations(errors.WrapC(err, ErrValidation, "%s", err.Error()), violations(err, "", desc)...)
+}
+
+// violations 展开嵌套消息的错误, 字段路径为 profile.name, items[0].id
+func violations(err error, prefix string, desc protoreflect.MessageDescriptor) []errors.FieldViolation {
+	if me, ok := err.(multiError); ok {
+		var vs []errors.FieldViolation
+		for _, e := range me.AllErrors() {
+			vs = append(vs, violations(e, prefix, desc)...)
+		}
+		return vs
+	}
+	fe, ok := err.(fieldError)
+	if !ok {
+		return []errors.FieldViolation{{Field: strings.TrimSuffix(prefix, "."), Description: err.Error()}}
+	}
+	name, index := fe.Field(), ""
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name, index = name[:i], name[i:]
+	}
+	var sub protoreflect.MessageDescriptor
+	if fd := fieldByGoName(desc, name); fd != nil {
+		name = string(fd.Name())
+		if fd.IsMap() {
+			sub = fd.MapValue().Message()
+		} else {
+			sub = fd.Message()
+		}
+	}
+	field := prefix + name + index
+	switch cause := fe.Cause(); cause.(type) {
+	case multiError, fieldError:
+		return violations(cause, field+".", sub)
+	}
+	return []errors.FieldViolation{{Field: field, Description: fe.Reason()}}
+}
+
+// fieldByGoName protoc-gen-validate 的字段名是 go 字段名(UserId), 转换为 proto 字段名(user_id)
+func fieldByGoName(desc protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	if desc == nil {
+		return nil
+	}
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if strings.EqualFold(strings.ReplaceAll(string(fd.Name()), "_", ""), name) {
+			return fd
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package validate
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/cr-mao/lori/errors"
+)
+
+type helloRequest struct {
+	name string
+}
+
+func (r *helloRequest) Validate() error {
+	if r.name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	h := Validator()(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	if _, err := h(context.Background(), &helloRequest{}); !errors.IsCode(err, ErrValidation) ||
+		errors.ParseCoder(err).HTTPStatus() != http.StatusBadRequest {
+		t.Fatalf("expect validation error, got %v", err)
+	}
+	if reply, err := h(context.Background(), &helloRequest{name: "lori"}); err != nil || reply != "ok" {
+		t.Errorf("unexpected reply %v %v", reply, err)
+	}
+	// 没有实现 Validate 的请求直接放行
+	if _, err := h(context.Background(), &http.Request{}); err != nil {
+		t.Error(err)
+	}
+}
+
+// protoc-gen-validate 生成的错误
+type pgvError struct {
+	field  string
+	reason string
+	cause  error
+}
+
+func (e pgvError) Field() string  { return e.field }
+func (e pgvError) Reason() string { return e.reason }
+func (e pgvError) Cause() error   { return e.cause }
+func (e pgvError) Error() string  { return e.field + ": " + e.reason }
+
+type pgvMultiError []error
+
+func (m pgvMultiError) Error() string      { return m[0].Error() }
+func (m pgvMultiError) AllErrors() []error { return m }
+
+type pgvRequest struct {
+	*descriptorpb.DescriptorProto
+}
+
+func (r pgvRequest) Validate() error {
+	return errors.New("ValidateAll should be preferred")
+}
+
+func (r pgvRequest) ValidateAll() error {
+	return pgvMultiError{
+		pgvError{field: "Name", reason: "value length must be at least 1 runes"},
+		pgvError{field: "Field[1]", reason: "embedded message failed validation", cause: pgvMultiError{
+			pgvError{field: "TypeName", reason: "value must not be empty"},
+		}},
+	}
+}
+
+func TestValidateViolations(t *testing.T) {
+	err := Validate(pgvRequest{&descriptorpb.DescriptorProto{}})
+	if !errors.IsCode(err, ErrValidation) {
+		t.Fatalf("expect validation error, got %v", err)
+	}
+	want := []errors.FieldViolation{
+		{Field: "name", Description: "value length must be at least 1 runes"},
+		{Field: "field[1].type_name", Description: "value must not be empty"},
+	}
+	got := errors.Violations(err)
+	if len(got) != len(want) {
+		t.Fatalf("expect violations %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expect violation %v, got %v", want[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package bbr
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/cr-mao/lori/ratelimit"
+)
+
+var _ ratelimit.Limiter = (*BBR)(nil)
+
+// Option bbr 限流器选项
+type Option func(*options)
+
+type options struct {
+	// 滑动窗口时长
+	window time.Duration
+	// 窗口中桶的数量
+	bucket int
+	// cpu 使用率阈值 (千分比), 超过后开始根据在途请求数判断是否丢弃
+	cpuThreshold int64
+	// cpu 使用率获取函数, 默认从 cgroup (设置了 cpu 配额时) 或者 /proc/stat 采样
+	cpu func() int64
+}
+
+// WithWindow 设置滑动窗口时长, 默认 10s
+func WithWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.window = d
+	}
+}
+
+// WithBucket 设置窗口中桶的数量, 默认 100
+func WithBucket(b int) Option {
+	return func(o *options) {
+		o.bucket = b
+	}
+}
+
+// WithCPUThreshold 设置 cpu 使用率阈值 (千分比), 默认 800
+func WithCPUThreshold(threshold int64) Option {
+	return func(o *options) {
+		o.cpuThreshold = threshold
+	}
+}
+
+// WithCPU 自定义 cpu 使用率来源 (千分比), 默认从 cgroup 或者 /proc/stat 采样
+func WithCPU(cpu func() int64) Option {
+	return func(o *options) {
+		o.cpu = cpu
+	}
+}
+
+// BBR 自适应限流器, 参考 TCP BBR 拥塞控制:
+// 当 cpu 超过阈值时, 如果在途请求数超过 maxPass * minRT 估算出的系统容量, 则直接拒绝请求。
+type BBR struct {
+	opts            options
+	passStat        *window // 每个桶完成的请求数
+	rtStat          *window // 每个桶请求耗时(ms)
+	inFlight        int64
+	bucketPerSecond int64
+	prevDropTime    atomic.Value // 上次丢弃请求的时间, 冷却期 1s
+	now             func() time.Time
+}
+
+// NewLimiter 创建 bbr 限流器
+func NewLimiter(opts ...Option) *BBR {
+	o := options{
+		window:       10 * time.Second,
+		bucket:       100,
+		cpuThreshold: 800,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.cpu == nil {
+		startCPUProc()
+		o.cpu = CPUUsage
+	}
+	bucketDuration := o.window / time.Duration(o.bucket)
+	l := &BBR{
+		opts:            o,
+		bucketPerSecond: int64(time.Second / bucketDuration),
+		now:             time.Now,
+	}
+	l.passStat = newWindow(o.bucket, bucketDuration, l.clock)
+	l.rtStat = newWindow(o.bucket, bucketDuration, l.clock)
+	l.prevDropTime.Store(time.Time{})
+	return l
+}
+
+func (l *BBR) clock() time.Time {
+	return l.now()
+}
+
+// maxPass 窗口内单个桶的最大通过数
+func (l *BBR) maxPass() int64 {
+	maxPass := 1.0
+	l.passStat.reduce(func(b bucket) {
+		maxPass = math.Max(maxPass, b.sum)
+	})
+	return int64(maxPass)
+}
+
+// minRT 窗口内单个桶的最小平均耗时(ms)
+func (l *BBR) minRT() int64 {
+	minRT := math.MaxFloat64
+	l.rtStat.reduce(func(b bucket) {
+		if b.count == 0 {
+			return
+		}
+		minRT = math.Min(minRT, math.Ceil(b.sum/float64(b.count)))
+	})
+	if minRT == math.MaxFloat64 {
+		return 1
+	}
+	return int64(minRT)
+}
+
+// maxInFlight 估算系统能承载的最大在途请求数
+func (l *BBR) maxInFlight() int64 {
+	return int64(math.Floor(float64(l.maxPass()*l.minRT()*l.bucketPerSecond)/1000.0 + 0.5))
+}
+
+func (l *BBR) shouldDrop() bool {
+	now := l.now()
+	prevDrop, _ := l.prevDropTime.Load().(time.Time)
+	inFlight := atomic.LoadInt64(&l.inFlight)
+	if l.opts.cpu() < l.opts.cpuThreshold {
+		if prevDrop.IsZero() {
+			return false
+		}
+		// cpu 刚降下来时仍保持 1s 的冷却, 避免抖动
+		if now.Sub(prevDrop) <= time.Second {
+			return inFlight > 1 && inFlight > l.maxInFlight()
+		}
+		l.prevDropTime.Store(time.Time{})
+		return false
+	}
+	drop := inFlight > 1 && inFlight > l.maxInFlight()
+	if drop && prevDrop.IsZero() {
+		l.prevDropTime.Store(now)
+	}
+	return drop
+}
+
+// Allow 判断请求是否放行
+func (l *BBR) Allow() (ratelimit.DoneFunc, error) {
+	if l.shouldDrop() {
+		return nil, ratelimit.ErrLimitExceed
+	}
+	atomic.AddInt64(&l.inFlight, 1)
+	start := l.now()
+	return func(ratelimit.DoneInfo) {
+		rt := math.Ceil(float64(l.now().Sub(start)) / float64(time.Millisecond))
+		if rt > 0 {
+			l.rtStat.add(rt)
+		}
+		atomic.AddInt64(&l.inFlight, -1)
+		l.passStat.add(1)
+	}, nil
+}
+
+// Stat bbr 当前状态, 方便打点监控
+type Stat struct {
+	CPU         int64
+	InFlight    int64
+	MaxInFlight int64
+	MinRT       int64
+	MaxPass     int64
+}
+
+// Stat 返回限流器当前的统计信息
+func (l *BBR) Stat() Stat {
+	return Stat{
+		CPU:         l.opts.cpu(),
+		InFlight:    atomic.LoadInt64(&l.inFlight),
+		MaxInFlight: l.maxInFlight(),
+		MinRT:       l.minRT(),
+		MaxPass:     l.maxPass(),
+	}
+}
@@ -0,0 +1,86 @@
+package bbr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cr-mao/lori/ratelimit"
+)
+
+func newTestLimiter(cpu *int64, now *time.Time) *BBR {
+	l := NewLimiter(
+		WithWindow(time.Second),
+		WithBucket(10),
+		WithCPU(func() int64 { return *cpu }),
+	)
+	l.now = func() time.Time { return *now }
+	l.passStat = newWindow(10, 100*time.Millisecond, l.clock)
+	l.rtStat = newWindow(10, 100*time.Millisecond, l.clock)
+	return l
+}
+
+func TestBBR_LowCPU(t *testing.T) {
+	cpu := int64(100)
+	now := time.Unix(100, 0)
+	l := newTestLimiter(&cpu, &now)
+	for i := 0; i < 100; i++ {
+		if _, err := l.Allow(); err != nil {
+			t.Fatalf("expect allow under low cpu, got %v", err)
+		}
+	}
+}
+
+func TestBBR_HighCPU(t *testing.T) {
+	cpu := int64(100)
+	now := time.Unix(100, 0)
+	l := newTestLimiter(&cpu, &now)
+
+	// 每个桶完成 10 个请求, 每个耗时 1ms
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			done, err := l.Allow()
+			if err != nil {
+				t.Fatal(err)
+			}
+			now = now.Add(time.Millisecond)
+			done(ratelimit.DoneInfo{})
+		}
+		now = now.Add(90 * time.Millisecond)
+	}
+	// maxPass=10 minRT=1 => maxInFlight=10*1*10/1000 -> 0, 任意在途请求都会被拒绝
+	cpu = 900
+	var dones []ratelimit.DoneFunc
+	var dropped bool
+	for i := 0; i < 5; i++ {
+		done, err := l.Allow()
+		if err == ratelimit.ErrLimitExceed {
+			dropped = true
+			break
+		}
+		dones = append(dones, done)
+	}
+	if !dropped {
+		t.Errorf("expect drop under high cpu, stat: %+v", l.Stat())
+	}
+	for _, done := range dones {
+		done(ratelimit.DoneInfo{})
+	}
+	if _, err := l.Allow(); err != nil {
+		t.Errorf("expect allow after in-flight drained, got %v", err)
+	}
+}
+
+func TestWindow_Reduce(t *testing.T) {
+	now := time.Unix(100, 0)
+	w := newWindow(5, 100*time.Millisecond, func() time.Time { return now })
+	for i := 0; i < 5; i++ {
+		w.add(float64(i + 1))
+		now = now.Add(100 * time.Millisecond)
+	}
+	var sum float64
+	w.reduce(func(b bucket) { sum += b.sum })
+	// 最旧的桶 (1) 已过期
+	if sum != 2+3+4+5 {
+		t.Errorf("expect %v, got %v", 2+3+4+5, sum)
+	}
+}
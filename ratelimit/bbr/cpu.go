@@ -0,0 +1,67 @@
+package bbr
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cr-mao/lori/log"
+)
+
+const (
+	cpuSampleInterval = 500 * time.Millisecond
+	// cpu 使用率的滑动平均衰减系数
+	cpuDecay = 0.95
+)
+
+var (
+	// cpu 使用率, 千分比 (0~1000)
+	cpuUsage int64
+	cpuOnce  sync.Once
+)
+
+// startCPUProc 后台定时采样 cpu 使用率, 只启动一次
+func startCPUProc() {
+	cpuOnce.Do(func() {
+		go func() {
+			prevTotal, prevBusy, err := readCPUStat()
+			if err != nil {
+				log.Warnf("[bbr] cpu usage is not available, only in-flight is used: %v", err)
+				return
+			}
+			ticker := time.NewTicker(cpuSampleInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				total, busy, err := readCPUStat()
+				if err != nil {
+					continue
+				}
+				if total <= prevTotal {
+					continue
+				}
+				usage := cpuPermille(prevTotal, prevBusy, total, busy)
+				prevTotal, prevBusy = total, busy
+				prev := atomic.LoadInt64(&cpuUsage)
+				atomic.StoreInt64(&cpuUsage, int64(float64(prev)*cpuDecay+float64(usage)*(1-cpuDecay)))
+			}
+		}()
+	})
+}
+
+// cpuPermille 两次采样之间的使用率 (千分比), 限制在 0~1000:
+// /proc/stat 的 iowait 可能变小, cgroup 的用量也可能短暂超过配额
+func cpuPermille(prevTotal, prevBusy, total, busy uint64) int64 {
+	usage := int64(1000 * (float64(busy) - float64(prevBusy)) / float64(total-prevTotal))
+	if usage < 0 {
+		return 0
+	}
+	if usage > 1000 {
+		return 1000
+	}
+	return usage
+}
+
+// CPUUsage 返回最近的 cpu 使用率 (千分比)
+func CPUUsage() int64 {
+	return atomic.LoadInt64(&cpuUsage)
+}
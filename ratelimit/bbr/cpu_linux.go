@@ -0,0 +1,244 @@
+//go:build linux
+// +build linux
+
+package bbr
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	procStatPath   = "/proc/stat"
+	procCgroupPath = "/proc/self/cgroup"
+	cgroupRoot     = "/sys/fs/cgroup"
+)
+
+var (
+	cgroupOnce sync.Once
+	cgroupCPU  *cgroupReader
+)
+
+// readCPUStat 返回累计的 cpu 总时间和忙碌时间, 只在采样 goroutine 中调用;
+// cgroup 设置了 cpu 配额时(比如容器)按 cgroup 的用量和配额计算, 否则读取 /proc/stat
+func readCPUStat() (total, busy uint64, err error) {
+	cgroupOnce.Do(func() {
+		cgroupCPU = detectCgroup(procCgroupPath, cgroupRoot)
+	})
+	if cgroupCPU != nil {
+		return cgroupCPU.stat(time.Now())
+	}
+	return readProcStat(procStatPath)
+}
+
+// readProcStat 读取 /proc/stat 第一行, 返回总的 cpu 时间和非空闲时间
+func readProcStat(path string) (total, busy uint64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	if !sc.Scan() {
+		return 0, 0, errors.New("empty /proc/stat")
+	}
+	fields := strings.Fields(sc.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, errors.New("invalid /proc/stat")
+	}
+	var idle uint64
+	for i, v := range fields[1:] {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		total += n
+		// idle 和 iowait
+		if i == 3 || i == 4 {
+			idle += n
+		}
+	}
+	return total, total - idle, nil
+}
+
+// cgroupReader 从 cgroup 读取 cpu 用量和配额
+type cgroupReader struct {
+	usage func() (uint64, error)  // 累计使用的 cpu 时间, 纳秒
+	quota func() (float64, error) // 配额的核数, 没有限制时为 0
+	last  time.Time
+	total float64 // 按配额累计的可用 cpu 时间, 纳秒
+}
+
+// stat 可用时间按两次调用之间的时间乘以当时的配额累加, 配额运行时修改也保持单调
+func (c *cgroupReader) stat(now time.Time) (total, busy uint64, err error) {
+	if busy, err = c.usage(); err != nil {
+		return 0, 0, err
+	}
+	cpus, err := c.quota()
+	if err != nil {
+		return 0, 0, err
+	}
+	if cpus <= 0 {
+		cpus = float64(runtime.NumCPU())
+	}
+	if !c.last.IsZero() {
+		c.total += float64(now.Sub(c.last)) * cpus
+	}
+	c.last = now
+	return uint64(c.total), busy, nil
+}
+
+// detectCgroup 当前进程所在的 cgroup 设置了 cpu 配额时返回 reader, 优先 v2, 其次 v1 (cpu, cpuacct)
+func detectCgroup(procCgroup, root string) *cgroupReader {
+	data, err := os.ReadFile(procCgroup)
+	if err != nil {
+		return nil
+	}
+	var v2Path, cpuPath, acctPath string
+	var v2 bool
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			v2, v2Path = true, parts[2]
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			switch controller {
+			case "cpu":
+				cpuPath = parts[2]
+			case "cpuacct":
+				acctPath = parts[2]
+			}
+		}
+	}
+
+	// 有 cgroup namespace 时挂载点就是进程所在的 cgroup, 所以也尝试根目录
+	if cpuPath != "" && acctPath != "" {
+		cpuDir := findDir("cpu.cfs_quota_us", filepath.Join(root, "cpu", cpuPath), filepath.Join(root, "cpu,cpuacct", cpuPath),
+			filepath.Join(root, "cpu"), filepath.Join(root, "cpu,cpuacct"))
+		acctDir := findDir("cpuacct.usage", filepath.Join(root, "cpuacct", acctPath), filepath.Join(root, "cpu,cpuacct", acctPath),
+			filepath.Join(root, "cpuacct"), filepath.Join(root, "cpu,cpuacct"))
+		if cpuDir != "" && acctDir != "" {
+			if r := newCgroupReader(
+				func() (uint64, error) { return readUint(filepath.Join(acctDir, "cpuacct.usage")) },
+				func() (float64, error) { return readCFSQuota(cpuDir) },
+			); r != nil {
+				return r
+			}
+		}
+	}
+	if v2 {
+		if dir := findDir("cpu.max", filepath.Join(root, v2Path), root); dir != "" {
+			return newCgroupReader(
+				func() (uint64, error) { return readCPUStatUsage(filepath.Join(dir, "cpu.stat")) },
+				func() (float64, error) { return readCPUMax(filepath.Join(dir, "cpu.max")) },
+			)
+		}
+	}
+	return nil
+}
+
+// newCgroupReader 配额和用量都能读取并且设置了配额时才使用 cgroup
+func newCgroupReader(usage func() (uint64, error), quota func() (float64, error)) *cgroupReader {
+	if cpus, err := quota(); err != nil || cpus <= 0 {
+		return nil
+	}
+	if _, err := usage(); err != nil {
+		return nil
+	}
+	return &cgroupReader{usage: usage, quota: quota}
+}
+
+// findDir 返回第一个包含 file 的目录
+func findDir(file string, dirs ...string) string {
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+// readCPUMax cgroup v2 的 cpu.max: "max 100000" 或者 "200000 100000"
+func readCPUMax(path string) (float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return 0, errors.New("invalid " + path)
+	}
+	if fields[0] == "max" {
+		return 0, nil
+	}
+	return parseQuota(fields[0], fields[1])
+}
+
+// readCPUStatUsage cgroup v2 的 cpu.stat 中的 usage_usec, 返回纳秒
+func readCPUStatUsage(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			n, err := strconv.ParseUint(fields[1], 10, 64)
+			return n * uint64(time.Microsecond), err
+		}
+	}
+	return 0, errors.New("usage_usec not found in " + path)
+}
+
+// readCFSQuota cgroup v1 的 cpu.cfs_quota_us / cpu.cfs_period_us, quota 为 -1 时没有限制
+func readCFSQuota(dir string) (float64, error) {
+	quota, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_quota_us"))
+	if err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(string(quota)) == "-1" {
+		return 0, nil
+	}
+	period, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_period_us"))
+	if err != nil {
+		return 0, err
+	}
+	return parseQuota(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+func parseQuota(quota, period string) (float64, error) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil {
+		return 0, err
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil {
+		return 0, err
+	}
+	if p <= 0 {
+		return 0, errors.New("invalid cpu period " + period)
+	}
+	return q / p, nil
+}
+
+func readUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
@@ -0,0 +1,112 @@
+//go:build linux
+// +build linux
+
+package bbr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDetectCgroupV2(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/cgroup":               "0::/kubepods/pod1\n",
+		"fs/kubepods/pod1/cpu.max":  "200000 100000\n",
+		"fs/kubepods/pod1/cpu.stat": "usage_usec 1000000\nuser_usec 600000\n",
+		"unlimited/proc/cgroup":     "0::/\n",
+		"unlimited/fs/cpu.max":      "max 100000\n",
+		"unlimited/fs/cpu.stat":     "usage_usec 1000000\n",
+		"missing/proc/cgroup":       "0::/\n",
+		"namespaced/proc/cgroup":    "0::/\n",
+		"namespaced/fs/cpu.max":     "50000 100000\n",
+		"namespaced/fs/cpu.stat":    "usage_usec 0\n",
+	})
+	r := detectCgroup(filepath.Join(root, "proc/cgroup"), filepath.Join(root, "fs"))
+	if r == nil {
+		t.Fatal("expect cgroup v2 with quota detected")
+	}
+	now := time.Now()
+	if _, busy, err := r.stat(now); err != nil || busy != uint64(time.Second) {
+		t.Fatalf("unexpected stat %v %v", busy, err)
+	}
+	// 2 核配额, 100ms 内用了 100ms cpu, 使用率 50%
+	writeFiles(t, root, map[string]string{"fs/kubepods/pod1/cpu.stat": "usage_usec 1100000\n"})
+	total, busy, err := r.stat(now.Add(100 * time.Millisecond))
+	if err != nil || total != uint64(200*time.Millisecond) {
+		t.Fatalf("unexpected stat %v %v", total, err)
+	}
+	if usage := cpuPermille(0, uint64(time.Second), total, busy); usage != 500 {
+		t.Errorf("expect 500, got %d", usage)
+	}
+
+	if r := detectCgroup(filepath.Join(root, "unlimited/proc/cgroup"), filepath.Join(root, "unlimited/fs")); r != nil {
+		t.Error("expect /proc/stat used without cpu quota")
+	}
+	if r := detectCgroup(filepath.Join(root, "missing/proc/cgroup"), filepath.Join(root, "missing/fs")); r != nil {
+		t.Error("expect nil without cgroup files")
+	}
+	if r := detectCgroup(filepath.Join(root, "namespaced/proc/cgroup"), filepath.Join(root, "namespaced/fs")); r == nil {
+		t.Error("expect cgroup detected at the mount root")
+	}
+}
+
+func TestDetectCgroupV1(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/cgroup": "11:memory:/docker/abc\n4:cpu,cpuacct:/docker/abc\n",
+		"fs/cpu,cpuacct/docker/abc/cpu.cfs_quota_us":  "150000\n",
+		"fs/cpu,cpuacct/docker/abc/cpu.cfs_period_us": "100000\n",
+		"fs/cpu,cpuacct/docker/abc/cpuacct.usage":     "5000\n",
+	})
+	r := detectCgroup(filepath.Join(root, "proc/cgroup"), filepath.Join(root, "fs"))
+	if r == nil {
+		t.Fatal("expect cgroup v1 with quota detected")
+	}
+	if cpus, err := r.quota(); err != nil || cpus != 1.5 {
+		t.Errorf("expect 1.5 cpus, got %v %v", cpus, err)
+	}
+	if busy, err := r.usage(); err != nil || busy != 5000 {
+		t.Errorf("expect 5000ns, got %v %v", busy, err)
+	}
+
+	writeFiles(t, root, map[string]string{"fs/cpu,cpuacct/docker/abc/cpu.cfs_quota_us": "-1\n"})
+	if r := detectCgroup(filepath.Join(root, "proc/cgroup"), filepath.Join(root, "fs")); r != nil {
+		t.Error("expect /proc/stat used without cpu quota")
+	}
+}
+
+func TestReadProcStat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stat")
+	writeFiles(t, filepath.Dir(path), map[string]string{
+		"stat": "cpu  100 0 100 700 100 0 0 0 0 0\ncpu0 50 0 50 350 50 0 0 0 0 0\n",
+	})
+	total, busy, err := readProcStat(path)
+	if err != nil || total != 1000 || busy != 200 {
+		t.Errorf("unexpected stat %d %d %v", total, busy, err)
+	}
+}
+
+func TestCPUPermille(t *testing.T) {
+	if got := cpuPermille(0, 100, 1000, 50); got != 0 {
+		t.Errorf("expect decreasing busy clamped to 0, got %d", got)
+	}
+	if got := cpuPermille(0, 0, 1000, 1200); got != 1000 {
+		t.Errorf("expect usage over quota clamped to 1000, got %d", got)
+	}
+}
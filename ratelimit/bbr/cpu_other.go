@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package bbr
+
+import "errors"
+
+func readCPUStat() (total, busy uint64, err error) {
+	return 0, 0, errors.New("cpu stat is only supported on linux")
+}
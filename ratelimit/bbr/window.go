@@ -0,0 +1,86 @@
+package bbr
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket 滑动窗口中的一个桶
+type bucket struct {
+	sum   float64
+	count int64
+}
+
+func (b *bucket) reset() {
+	b.sum = 0
+	b.count = 0
+}
+
+// window 基于环形数组的滑动窗口, 每个桶统计 bucketDuration 时间内的数据
+type window struct {
+	mu             sync.RWMutex
+	buckets        []bucket
+	offset         int // 当前桶下标
+	bucketDuration time.Duration
+	lastAppend     time.Time
+	now            func() time.Time
+}
+
+func newWindow(size int, bucketDuration time.Duration, now func() time.Time) *window {
+	return &window{
+		buckets:        make([]bucket, size),
+		bucketDuration: bucketDuration,
+		lastAppend:     now(),
+		now:            now,
+	}
+}
+
+// span 距离上次写入过去了多少个桶
+func (w *window) span(now time.Time) int {
+	v := int(now.Sub(w.lastAppend) / w.bucketDuration)
+	if v > -1 {
+		return v
+	}
+	return len(w.buckets)
+}
+
+// add 向当前桶中累加 v, 过期的桶会被清空
+func (w *window) add(v float64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	size := len(w.buckets)
+	if span := w.span(w.now()); span > 0 {
+		w.lastAppend = w.lastAppend.Add(time.Duration(span) * w.bucketDuration)
+		s := span
+		if s > size {
+			s = size
+		}
+		for i := 1; i <= s; i++ {
+			w.buckets[(w.offset+i)%size].reset()
+		}
+		w.offset = (w.offset + span) % size
+	}
+	w.buckets[w.offset].sum += v
+	w.buckets[w.offset].count++
+}
+
+// reduce 遍历已经完成统计的桶 (不包含当前正在写入的桶)
+func (w *window) reduce(f func(b bucket)) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	size := len(w.buckets)
+	span := w.span(w.now())
+	// 未过期且已完成的桶数量, span 为 0 时当前桶还在写入, 不参与统计
+	count := size - span
+	if span == 0 {
+		count = size - 1
+	}
+	if count <= 0 {
+		return
+	}
+	// 最旧的未过期桶
+	start := (w.offset + span + 1) % size
+	for i := 0; i < count; i++ {
+		f(w.buckets[(start+i)%size])
+	}
+}
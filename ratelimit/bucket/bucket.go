@@ -0,0 +1,63 @@
+package bucket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cr-mao/lori/ratelimit"
+)
+
+var _ ratelimit.Limiter = (*Bucket)(nil)
+
+// Bucket 令牌桶限流器, 按固定速率生成令牌, 最多积攒 burst 个
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64 // 每秒生成的令牌数
+	burst  float64 // 桶容量
+	tokens float64 // 当前令牌数
+	last   time.Time
+	now    func() time.Time
+}
+
+// New 创建令牌桶, rate 每秒令牌数, burst 桶容量 (<=0 时取 rate)
+func New(rate float64, burst int) *Bucket {
+	b := float64(burst)
+	if b <= 0 {
+		b = rate
+	}
+	if b < 1 {
+		b = 1
+	}
+	return &Bucket{
+		rate:   rate,
+		burst:  b,
+		tokens: b,
+		now:    time.Now,
+	}
+}
+
+// Allow 取一个令牌, 取不到返回 ratelimit.ErrLimitExceed
+func (b *Bucket) Allow() (ratelimit.DoneFunc, error) {
+	if !b.take() {
+		return nil, ratelimit.ErrLimitExceed
+	}
+	return func(ratelimit.DoneInfo) {}, nil
+}
+
+func (b *Bucket) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
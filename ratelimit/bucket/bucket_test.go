@@ -0,0 +1,31 @@
+package bucket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cr-mao/lori/ratelimit"
+)
+
+func TestBucket_Allow(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := New(10, 2)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := b.Allow(); err != nil {
+			t.Fatalf("expect allow, got %v", err)
+		}
+	}
+	if _, err := b.Allow(); err != ratelimit.ErrLimitExceed {
+		t.Errorf("expect %v, got %v", ratelimit.ErrLimitExceed, err)
+	}
+	// 100ms 生成一个令牌
+	now = now.Add(100 * time.Millisecond)
+	if _, err := b.Allow(); err != nil {
+		t.Errorf("expect allow after refill, got %v", err)
+	}
+	if _, err := b.Allow(); err != ratelimit.ErrLimitExceed {
+		t.Errorf("expect %v, got %v", ratelimit.ErrLimitExceed, err)
+	}
+}
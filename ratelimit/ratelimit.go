@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"net/http"
+
+	"github.com/cr-mao/lori/errors"
+)
+
+// ErrLimitExceed 请求被限流器拒绝
+var ErrLimitExceed = errors.New("rate limit exceeded")
+
+// ErrPanic 处理请求时 panic, 作为 DoneInfo.Err 回传给限流器
+var ErrPanic = errors.New("handler panic")
+
+// ErrRateLimited 请求被限流, 返回 429, grpc 为 RESOURCE_EXHAUSTED
+const ErrRateLimited = 100429
+
+func init() {
+	errors.Register(errors.NewReasonCoder(ErrRateLimited, http.StatusTooManyRequests, "RATE_LIMITED", "Too many requests, please try again later", ""))
+}
+
+// DoneInfo 请求结束时回传给限流器的信息
+type DoneInfo struct {
+	Err error
+}
+
+// DoneFunc 请求处理完成后调用, 用于统计耗时和在途请求数
+type DoneFunc func(DoneInfo)
+
+// Limiter 限流器接口
+// Allow 返回 ErrLimitExceed 表示请求应该被拒绝, 否则处理完请求后必须调用 DoneFunc
+type Limiter interface {
+	Allow() (DoneFunc, error)
+}
+
+// Rules 按 key 指定的静态限流器, key 为 grpc method (/helloworld.Greeter/SayHello)
+// 或 gin 路由模板 (/v1/user/:id)
+type Rules map[string]Limiter
+
+// Match 返回 key 对应的限流器, 没有则返回 nil
+func (r Rules) Match(key string) Limiter {
+	if r == nil {
+		return nil
+	}
+	return r[key]
+}
+
+// Allow 依次经过多个限流器, 任意一个拒绝则整体拒绝, 已放行的会被回调 DoneFunc
+func Allow(limiters ...Limiter) (DoneFunc, error) {
+	dones := make([]DoneFunc, 0, len(limiters))
+	done := func(di DoneInfo) {
+		for _, d := range dones {
+			d(di)
+		}
+	}
+	for _, l := range limiters {
+		if l == nil {
+			continue
+		}
+		d, err := l.Allow()
+		if err != nil {
+			done(DoneInfo{Err: err})
+			return nil, err
+		}
+		dones = append(dones, d)
+	}
+	return done, nil
+}
@@ -0,0 +1,109 @@
+package hedge
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+)
+
+// Name 负载均衡器名称, 对冲请求需要使用这个均衡器, 才能让对冲副本避开已经选中的节点
+const Name = "lori_hedge"
+
+func init() {
+	balancer.Register(base.NewBalancerBuilder(Name, &pickerBuilder{}, base.Config{HealthCheck: true}))
+}
+
+type (
+	excludeKey struct{}
+	pickedKey  struct{}
+)
+
+// Picked 记录本次调用被选中的节点地址
+type Picked struct {
+	mu   sync.Mutex
+	addr string
+}
+
+// Addr 返回被选中的节点地址, 还没选中时为空
+func (p *Picked) Addr() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.addr
+}
+
+func (p *Picked) set(addr string) {
+	p.mu.Lock()
+	p.addr = addr
+	p.mu.Unlock()
+}
+
+// WithPicked 返回携带 Picked 的 context, 调用完成后可以知道请求发往了哪个节点
+func WithPicked(ctx context.Context) (context.Context, *Picked) {
+	p := &Picked{}
+	return context.WithValue(ctx, pickedKey{}, p), p
+}
+
+// WithExclude 设置本次调用需要避开的节点地址
+func WithExclude(ctx context.Context, addrs ...string) context.Context {
+	return context.WithValue(ctx, excludeKey{}, addrs)
+}
+
+type pickerBuilder struct{}
+
+// Build 只在有可用连接时返回 picker
+func (*pickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	nodes := make([]node, 0, len(info.ReadySCs))
+	for sc, sci := range info.ReadySCs {
+		nodes = append(nodes, node{sc: sc, addr: sci.Address.Addr})
+	}
+	return &picker{
+		nodes: nodes,
+		next:  uint32(rand.Intn(len(nodes))),
+	}
+}
+
+type node struct {
+	sc   balancer.SubConn
+	addr string
+}
+
+// picker 轮询选择节点, 跳过 context 中要求避开的节点
+type picker struct {
+	nodes []node
+	next  uint32
+}
+
+func (p *picker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	n := uint32(len(p.nodes))
+	start := atomic.AddUint32(&p.next, 1)
+	excluded, _ := info.Ctx.Value(excludeKey{}).([]string)
+	// 所有节点都被排除时, 退化为普通轮询
+	chosen := p.nodes[start%n]
+	for i := uint32(0); i < n; i++ {
+		nd := p.nodes[(start+i)%n]
+		if !contains(excluded, nd.addr) {
+			chosen = nd
+			break
+		}
+	}
+	if picked, ok := info.Ctx.Value(pickedKey{}).(*Picked); ok {
+		picked.set(chosen.addr)
+	}
+	return balancer.PickResult{SubConn: chosen.sc}, nil
+}
+
+func contains(addrs []string, addr string) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package hedge
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/balancer"
+)
+
+type fakeSubConn struct {
+	balancer.SubConn
+	addr string
+}
+
+func TestPicker_Exclude(t *testing.T) {
+	p := &picker{nodes: []node{
+		{sc: &fakeSubConn{addr: "a"}, addr: "a"},
+		{sc: &fakeSubConn{addr: "b"}, addr: "b"},
+	}}
+	for i := 0; i < 10; i++ {
+		ctx, picked := WithPicked(WithExclude(context.Background(), "a"))
+		res, err := p.Pick(balancer.PickInfo{Ctx: ctx})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.SubConn.(*fakeSubConn).addr; got != "b" {
+			t.Errorf("expect %v, got %v", "b", got)
+		}
+		if picked.Addr() != "b" {
+			t.Errorf("expect picked %v, got %v", "b", picked.Addr())
+		}
+	}
+	// 全部排除时仍然可以选出节点
+	ctx := WithExclude(context.Background(), "a", "b")
+	if _, err := p.Pick(balancer.PickInfo{Ctx: ctx}); err != nil {
+		t.Errorf("expect pick when all excluded, got %v", err)
+	}
+}
@@ -7,12 +7,14 @@ import (
 
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer/roundrobin"
 	"google.golang.org/grpc/credentials"
 	grpcinsecure "google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/resolver"
 
 	"github.com/cr-mao/lori/metric"
 	"github.com/cr-mao/lori/registry"
+	"github.com/cr-mao/lori/transport/grpc/balancer/hedge"
 	"github.com/cr-mao/lori/transport/grpc/resolver/direct"
 	"github.com/cr-mao/lori/transport/grpc/resolver/discovery"
 )
@@ -22,6 +24,10 @@ type ClientOption func(o *clientOptions)
 type clientOptions struct {
 	endpoint string
 	timeout  time.Duration
+	// 流的超时时间(整个流的生命周期), 默认0不限制
+	streamTimeout time.Duration
+	// 上游带了 deadline 时给自己预留的时间, 下游超时 = 剩余时间 - reserve
+	deadlineReserve time.Duration
 	// discovery接口
 	discovery  registry.Discovery
 	unaryInts  []grpc.UnaryClientInterceptor
@@ -32,6 +38,8 @@ type clientOptions struct {
 
 	balancerName  string
 	enableTracing bool
+	hedgingOpts   []HedgingOption // 对冲请求选项, 为nil时不开启
+	cacheKey      string          // ClientManager 缓存连接的 key
 }
 
 func WithClientMetric(metric metric.GrpcClientMetric) ClientOption {
@@ -60,6 +68,21 @@ func WithClientTimeout(timeout time.Duration) ClientOption {
 	}
 }
 
+// 设置流的超时时间, 是整个流的生命周期
+func WithClientStreamTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.streamTimeout = timeout
+	}
+}
+
+// 设置 deadline 预留时间, 上游带了 deadline 时, 下游请求的超时 = 剩余时间 - reserve,
+// 剩余时间不够时直接返回 DEADLINE_EXCEEDED
+func WithClientDeadlineReserve(reserve time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.deadlineReserve = reserve
+	}
+}
+
 // 设置服务发现
 func WithClientDiscovery(d registry.Discovery) ClientOption {
 	return func(o *clientOptions) {
@@ -88,6 +111,22 @@ func WithClientOptions(opts ...grpc.DialOption) ClientOption {
 	}
 }
 
+// 开启对冲请求, 需要通过 WithHedgingMethods 指定幂等的方法,
+// 负载均衡器为默认的 round_robin 时会切换为 hedge.Name, 让对冲副本避开第一个节点
+func WithClientHedging(opts ...HedgingOption) ClientOption {
+	return func(o *clientOptions) {
+		o.hedgingOpts = append([]HedgingOption{}, opts...)
+	}
+}
+
+// 设置 ClientManager 缓存连接的 key。dial 选项, 拦截器, 对冲选项是函数, 无法比较是否相同,
+// 使用了这些选项时需要调用方指定 key, key 相同的选项必须相同; 不指定时每次 Get 都新建连接, release 后关闭
+func WithClientCacheKey(key string) ClientOption {
+	return func(o *clientOptions) {
+		o.cacheKey = key
+	}
+}
+
 // 设置负载均衡器
 func WithBalancerName(name string) ClientOption {
 	return func(o *clientOptions) {
@@ -108,32 +147,53 @@ func Dial(ctx context.Context, opts ...ClientOption) (*grpc.ClientConn, error) {
 }
 
 func dial(ctx context.Context, insecure bool, opts ...ClientOption) (*grpc.ClientConn, error) {
+	return dialOptions(ctx, insecure, newClientOptions(opts...))
+}
+
+func newClientOptions(opts ...ClientOption) clientOptions {
 	options := clientOptions{
 		timeout:       2000 * time.Millisecond,
-		balancerName:  "round_robin",
+		balancerName:  roundrobin.Name,
 		enableTracing: true,
 	}
-
 	for _, o := range opts {
 		o(&options)
 	}
+	return options
+}
 
-	// 超时中间件
+func dialOptions(ctx context.Context, insecure bool, options clientOptions) (*grpc.ClientConn, error) {
+	// 超时中间件, metadata 透传
 	ints := []grpc.UnaryClientInterceptor{
-		clientTimeoutInterceptor(options.timeout),
+		unaryClientErrorInterceptor, //从 grpc status details 还原 lori 错误码
+		clientTimeoutInterceptor(options.timeout, options.deadlineReserve),
+		unaryClientMetadataInterceptor,
+	}
+	streamInts := []grpc.StreamClientInterceptor{
+		streamClientErrorInterceptor,
+		clientStreamTimeoutInterceptor(options.streamTimeout, options.deadlineReserve),
+		streamClientMetadataInterceptor,
 	}
 	if options.enableTracing {
 		ints = append(ints, otelgrpc.UnaryClientInterceptor())
+		streamInts = append(streamInts, otelgrpc.StreamClientInterceptor())
 	}
 
-	streamInts := []grpc.StreamClientInterceptor{}
-
 	if len(options.unaryInts) > 0 {
 		ints = append(ints, options.unaryInts...)
 	}
 
 	if options.metric != nil {
 		ints = append(ints, options.metric.GrpcClientMetricInterceptors()...)
+		streamInts = append(streamInts, options.metric.GrpcClientMetricStreamInterceptors()...)
+	}
+
+	// 对冲放在最内层, 每次对冲共享外层的超时和链路
+	if options.hedgingOpts != nil {
+		ints = append(ints, HedgingInterceptor(options.hedgingOpts...))
+		if options.balancerName == roundrobin.Name {
+			options.balancerName = hedge.Name
+		}
 	}
 
 	if len(options.streamInts) > 0 {
@@ -0,0 +1,281 @@
+package grpc
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cr-mao/lori/metric/prometheus"
+	"github.com/cr-mao/lori/transport/grpc/balancer/hedge"
+)
+
+const (
+	// 计算分位数最少需要的样本数, 不够时使用固定延迟
+	hedgingMinSamples = 20
+	// 每个方法保留的最近耗时样本数
+	hedgingMaxSamples = 1000
+)
+
+// HedgingOption 对冲请求选项
+type HedgingOption func(*hedgingOptions)
+
+type hedgingOptions struct {
+	methods     []string      // 幂等的方法, 支持 /pkg.Service/* 通配
+	delay       time.Duration // 多久没有响应就发出对冲请求
+	percentile  float64       // 按历史耗时分位数决定延迟, 如 0.95
+	maxAttempts int           // 最多发出的请求数(包含第一次)
+	nonFatal    []codes.Code  // 遇到这些错误时立即发出下一次对冲
+	counter     prometheus.CounterVec
+}
+
+// WithHedgingMethods 设置可以对冲的幂等方法, 只有匹配的方法才会对冲
+// example: /helloworld.Greeter/SayHello, /helloworld.Greeter/*
+func WithHedgingMethods(methods ...string) HedgingOption {
+	return func(o *hedgingOptions) {
+		o.methods = methods
+	}
+}
+
+// WithHedgingDelay 固定的对冲延迟, 默认 100ms
+func WithHedgingDelay(delay time.Duration) HedgingOption {
+	return func(o *hedgingOptions) {
+		o.delay = delay
+	}
+}
+
+// WithHedgingPercentile 使用该方法历史耗时的分位数作为对冲延迟, 比如 0.95,
+// 样本不够时使用固定延迟
+func WithHedgingPercentile(p float64) HedgingOption {
+	return func(o *hedgingOptions) {
+		o.percentile = p
+	}
+}
+
+// WithHedgingMaxAttempts 最多发出的请求数, 包含第一次, 默认 2
+func WithHedgingMaxAttempts(n int) HedgingOption {
+	return func(o *hedgingOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// WithHedgingNonFatalCodes 遇到这些错误码时不等延迟, 立即发出下一次对冲, 默认 UNAVAILABLE
+func WithHedgingNonFatalCodes(cs ...codes.Code) HedgingOption {
+	return func(o *hedgingOptions) {
+		o.nonFatal = cs
+	}
+}
+
+// WithHedgingCounter 对冲次数打点, labels: method, result(sent|won)
+func WithHedgingCounter(counter prometheus.CounterVec) HedgingOption {
+	return func(o *hedgingOptions) {
+		o.counter = counter
+	}
+}
+
+type hedging struct {
+	opts    hedgingOptions
+	mu      sync.Mutex
+	samples map[string]*latencyRing
+}
+
+// HedgingInterceptor 对冲请求拦截器, 对幂等的一元请求, 超过延迟没有响应时向另一个节点再发一次,
+// 取第一个成功的响应并取消其他请求。需要配合 hedge.Name 负载均衡器使用, 对冲副本才会避开已选中的节点。
+// 注意: 各次请求共享 grpc.CallOption, grpc.Header/grpc.Peer 等输出型选项的结果以最后写入的为准。
+func HedgingInterceptor(opts ...HedgingOption) grpc.UnaryClientInterceptor {
+	h := &hedging{
+		opts: hedgingOptions{
+			delay:       100 * time.Millisecond,
+			maxAttempts: 2,
+			nonFatal:    []codes.Code{codes.Unavailable},
+		},
+		samples: make(map[string]*latencyRing),
+	}
+	for _, o := range opts {
+		o(&h.opts)
+	}
+	return h.intercept
+}
+
+type hedgingResult struct {
+	attempt int
+	reply   proto.Message
+	err     error
+}
+
+func (h *hedging) intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	msg, ok := reply.(proto.Message)
+	if !ok || h.opts.maxAttempts < 2 || !h.match(method) {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		start    = time.Now()
+		results  = make(chan hedgingResult, h.opts.maxAttempts)
+		picked   = make([]*hedge.Picked, 0, h.opts.maxAttempts)
+		attempts int
+		pending  int
+		lastErr  error
+	)
+	launch := func() {
+		// 避开之前请求已经选中的节点
+		excluded := make([]string, 0, len(picked))
+		for _, p := range picked {
+			if addr := p.Addr(); addr != "" {
+				excluded = append(excluded, addr)
+			}
+		}
+		actx, p := hedge.WithPicked(hedge.WithExclude(ctx, excluded...))
+		picked = append(picked, p)
+		// 每次请求使用独立的 reply, 避免并发写
+		r := proto.Clone(msg)
+		proto.Reset(r)
+		attempt := attempts
+		attempts++
+		pending++
+		go func() {
+			err := invoker(actx, method, req, r, cc, opts...)
+			results <- hedgingResult{attempt: attempt, reply: r, err: err}
+		}()
+		if attempt > 0 {
+			h.count(method, "sent")
+		}
+	}
+
+	launch()
+	timer := time.NewTimer(h.delay(method))
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			if attempts < h.opts.maxAttempts {
+				launch()
+				timer.Reset(h.delay(method))
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				h.observe(method, time.Since(start))
+				if res.attempt > 0 {
+					h.count(method, "won")
+				}
+				proto.Reset(msg)
+				proto.Merge(msg, res.reply)
+				return nil
+			}
+			lastErr = res.err
+			if !h.nonFatal(res.err) {
+				return res.err
+			}
+			if attempts < h.opts.maxAttempts {
+				launch()
+				continue
+			}
+			if pending == 0 {
+				return lastErr
+			}
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+func (h *hedging) match(method string) bool {
+	for _, m := range h.opts.methods {
+		if m == method {
+			return true
+		}
+		if strings.HasSuffix(m, "/*") && strings.HasPrefix(method, strings.TrimSuffix(m, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *hedging) nonFatal(err error) bool {
+	code := status.Code(err)
+	for _, c := range h.opts.nonFatal {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *hedging) count(method, result string) {
+	if h.opts.counter != nil {
+		h.opts.counter.Inc(method, result)
+	}
+}
+
+// delay 对冲延迟, 配置了分位数且样本足够时使用历史耗时的分位数
+func (h *hedging) delay(method string) time.Duration {
+	if h.opts.percentile <= 0 {
+		return h.opts.delay
+	}
+	h.mu.Lock()
+	ring, ok := h.samples[method]
+	h.mu.Unlock()
+	if !ok {
+		return h.opts.delay
+	}
+	if d, ok := ring.percentile(h.opts.percentile); ok {
+		return d
+	}
+	return h.opts.delay
+}
+
+func (h *hedging) observe(method string, d time.Duration) {
+	if h.opts.percentile <= 0 {
+		return
+	}
+	h.mu.Lock()
+	ring, ok := h.samples[method]
+	if !ok {
+		ring = &latencyRing{values: make([]time.Duration, 0, hedgingMaxSamples)}
+		h.samples[method] = ring
+	}
+	h.mu.Unlock()
+	ring.add(d)
+}
+
+// latencyRing 保存最近的请求耗时
+type latencyRing struct {
+	mu     sync.Mutex
+	values []time.Duration
+	next   int
+}
+
+func (r *latencyRing) add(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.values) < cap(r.values) {
+		r.values = append(r.values, d)
+		return
+	}
+	r.values[r.next] = d
+	r.next = (r.next + 1) % len(r.values)
+}
+
+func (r *latencyRing) percentile(p float64) (time.Duration, bool) {
+	r.mu.Lock()
+	if len(r.values) < hedgingMinSamples {
+		r.mu.Unlock()
+		return 0, false
+	}
+	sorted := make([]time.Duration, len(r.values))
+	copy(sorted, r.values)
+	r.mu.Unlock()
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx], true
+}
@@ -0,0 +1,71 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/cr-mao/lori/example/proto"
+)
+
+type delayGreeter struct {
+	proto.UnimplementedGreeterServer
+	delay time.Duration
+	name  string
+}
+
+func (g *delayGreeter) SayHello(ctx context.Context, r *proto.HelloRequest) (*proto.HelloResponse, error) {
+	select {
+	case <-time.After(g.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &proto.HelloResponse{Message: g.name}, nil
+}
+
+func startGreeter(t *testing.T, g proto.GreeterServer) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	proto.RegisterGreeterServer(s, g)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func TestHedgingInterceptor(t *testing.T) {
+	slow := startGreeter(t, &delayGreeter{delay: time.Second, name: "slow"})
+	fast := startGreeter(t, &delayGreeter{name: "fast"})
+
+	conn, err := DialInsecure(context.Background(),
+		WithClientEndpoint("direct:///"+slow+","+fast),
+		WithClientEnableTracing(false),
+		WithClientHedging(
+			WithHedgingMethods("/lori.example.proto.Greeter/*"),
+			WithHedgingDelay(20*time.Millisecond),
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := proto.NewGreeterClient(conn)
+	for i := 0; i < 4; i++ {
+		start := time.Now()
+		resp, err := client.SayHello(context.Background(), &proto.HelloRequest{Name: "lori"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Message != "fast" {
+			t.Errorf("expect %v, got %v", "fast", resp.Message)
+		}
+		if d := time.Since(start); d > 500*time.Millisecond {
+			t.Errorf("expect hedged response within 500ms, got %v", d)
+		}
+	}
+}
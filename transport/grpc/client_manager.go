@@ -0,0 +1,320 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/log"
+	"github.com/cr-mao/lori/metric/prometheus"
+)
+
+// ErrClientManagerClosed ClientManager 已经关闭
+var ErrClientManagerClosed = errors.New("grpc client manager closed")
+
+// ClientManagerOption ClientManager 选项
+type ClientManagerOption func(*ClientManager)
+
+// WithIdleTimeout 没有引用的连接空闲多久后关闭, 默认 5 分钟, <=0 表示不关闭
+func WithIdleTimeout(d time.Duration) ClientManagerOption {
+	return func(m *ClientManager) {
+		m.idleTimeout = d
+	}
+}
+
+// WithCheckInterval 检查空闲连接和上报连接状态的间隔, 默认 10s
+func WithCheckInterval(d time.Duration) ClientManagerOption {
+	return func(m *ClientManager) {
+		m.checkInterval = d
+	}
+}
+
+// WithConnStateGauge 连接状态打点, labels: target, state
+func WithConnStateGauge(gauge prometheus.GaugeVec) ClientManagerOption {
+	return func(m *ClientManager) {
+		m.stateGauge = gauge
+	}
+}
+
+// ClientManager 按 target 和选项缓存 ClientConn, 引用计数, 避免每次请求都 Dial 导致 resolver/watcher 泄漏。
+// 使用了 dial 选项, 拦截器或者对冲选项时需要通过 WithClientCacheKey 指定缓存 key, 否则不缓存。
+// 应用退出时关闭所有连接:
+//
+//	manager := grpc.NewClientManager()
+//	app := lori.New(lori.AfterStop(manager.Close))
+type ClientManager struct {
+	mu            sync.Mutex
+	conns         map[string]*managedConn
+	idleTimeout   time.Duration
+	checkInterval time.Duration
+	stateGauge    prometheus.GaugeVec
+	gaugeLabels   map[[2]string]struct{} // 上次上报过的 target,state
+	closed        bool
+	done          chan struct{}
+	uncached      int // 不缓存的连接的序号
+}
+
+type managedConn struct {
+	key       string
+	target    string
+	conn      *grpc.ClientConn
+	err       error
+	ready     chan struct{} // dial 完成后关闭
+	refs      int
+	idleSince time.Time
+	uncached  bool // 不缓存, 引用为 0 时立即关闭
+}
+
+// NewClientManager 创建连接管理器
+func NewClientManager(opts ...ClientManagerOption) *ClientManager {
+	m := &ClientManager{
+		conns:         make(map[string]*managedConn),
+		idleTimeout:   5 * time.Minute,
+		checkInterval: 10 * time.Second,
+		gaugeLabels:   make(map[[2]string]struct{}),
+		done:          make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(m)
+	}
+	go m.loop()
+	return m
+}
+
+// Get 获取 tls 连接, 用完后调用 release 归还, 不要直接 Close 返回的连接
+func (m *ClientManager) Get(ctx context.Context, opts ...ClientOption) (*grpc.ClientConn, func(), error) {
+	return m.get(ctx, false, opts...)
+}
+
+// GetInsecure 获取非 tls 连接, 用完后调用 release 归还, 不要直接 Close 返回的连接
+func (m *ClientManager) GetInsecure(ctx context.Context, opts ...ClientOption) (*grpc.ClientConn, func(), error) {
+	return m.get(ctx, true, opts...)
+}
+
+func (m *ClientManager) get(ctx context.Context, insecure bool, opts ...ClientOption) (*grpc.ClientConn, func(), error) {
+	options := newClientOptions(opts...)
+	key, cacheable := clientOptionsKey(insecure, options)
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, nil, ErrClientManagerClosed
+	}
+	if !cacheable {
+		m.uncached++
+		key = fmt.Sprintf("uncached#%d", m.uncached)
+	}
+	mc, ok := m.conns[key]
+	if !ok {
+		mc = &managedConn{key: key, target: options.endpoint, ready: make(chan struct{}), uncached: !cacheable}
+		m.conns[key] = mc
+	}
+	mc.refs++
+	m.mu.Unlock()
+
+	// dial 可能因为服务发现阻塞, 不持有锁
+	if !ok {
+		mc.conn, mc.err = dialOptions(ctx, insecure, options)
+		close(mc.ready)
+	}
+	select {
+	case <-mc.ready:
+	case <-ctx.Done():
+		m.release(mc)
+		return nil, nil, ctx.Err()
+	}
+	if mc.err != nil {
+		m.mu.Lock()
+		mc.refs--
+		if m.conns[key] == mc {
+			delete(m.conns, key)
+		}
+		m.mu.Unlock()
+		return nil, nil, mc.err
+	}
+	var once sync.Once
+	return mc.conn, func() { once.Do(func() { m.release(mc) }) }, nil
+}
+
+func (m *ClientManager) release(mc *managedConn) {
+	m.mu.Lock()
+	mc.refs--
+	if mc.refs > 0 {
+		m.mu.Unlock()
+		return
+	}
+	mc.refs = 0
+	mc.idleSince = time.Now()
+	if !mc.uncached || m.conns[mc.key] != mc {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.conns, mc.key)
+	m.mu.Unlock()
+
+	// 可能是 dial 还没完成时 ctx 取消
+	go func() {
+		<-mc.ready
+		if mc.conn == nil {
+			return
+		}
+		if err := mc.conn.Close(); err != nil {
+			log.Errorf("[gRPC] close uncached client conn %s error: %v", mc.target, err)
+		}
+	}()
+}
+
+// Close 关闭所有连接, 可以作为 lori.AfterStop 钩子
+func (m *ClientManager) Close(_ context.Context) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	conns := m.conns
+	m.conns = make(map[string]*managedConn)
+	m.mu.Unlock()
+	close(m.done)
+
+	var errs []error
+	for _, mc := range conns {
+		<-mc.ready
+		if mc.conn == nil {
+			continue
+		}
+		if err := mc.conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	log.Info("[gRPC] client manager closed")
+	return errors.NewAggregate(errs)
+}
+
+func (m *ClientManager) loop() {
+	ticker := time.NewTicker(m.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.closeIdle()
+			m.reportState()
+		}
+	}
+}
+
+// closeIdle 关闭空闲超时的连接
+func (m *ClientManager) closeIdle() {
+	if m.idleTimeout <= 0 {
+		return
+	}
+	var idle []*managedConn
+	m.mu.Lock()
+	for key, mc := range m.conns {
+		select {
+		case <-mc.ready:
+		default:
+			continue
+		}
+		if mc.refs == 0 && time.Since(mc.idleSince) >= m.idleTimeout {
+			delete(m.conns, key)
+			idle = append(idle, mc)
+		}
+	}
+	m.mu.Unlock()
+	for _, mc := range idle {
+		log.Infof("[gRPC] close idle client conn: %s", mc.target)
+		if err := mc.conn.Close(); err != nil {
+			log.Errorf("[gRPC] close idle client conn %s error: %v", mc.target, err)
+		}
+	}
+}
+
+// reportState 上报每个 target 各个状态的连接数
+func (m *ClientManager) reportState() {
+	if m.stateGauge == nil {
+		return
+	}
+	counts := make(map[[2]string]int)
+	m.mu.Lock()
+	for _, mc := range m.conns {
+		if mc.conn == nil {
+			continue
+		}
+		counts[[2]string{mc.target, mc.conn.GetState().String()}]++
+	}
+	m.mu.Unlock()
+	// 之前上报过现在没有的状态置 0
+	for labels := range m.gaugeLabels {
+		if _, ok := counts[labels]; !ok {
+			m.stateGauge.Set(0, labels[0], labels[1])
+			delete(m.gaugeLabels, labels)
+		}
+	}
+	for labels, n := range counts {
+		m.stateGauge.Set(float64(n), labels[0], labels[1])
+		m.gaugeLabels[labels] = struct{}{}
+	}
+}
+
+// State 返回各连接的状态, key 为 target
+func (m *ClientManager) State() map[string][]connectivity.State {
+	states := make(map[string][]connectivity.State)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, mc := range m.conns {
+		if mc.conn != nil {
+			states[mc.target] = append(states[mc.target], mc.conn.GetState())
+		}
+	}
+	return states
+}
+
+// clientOptionsKey 根据选项生成缓存 key, 对象按实例区分。
+// dial 选项, 拦截器, 对冲选项是函数, 同一个函数捕获不同变量时
+// (如 grpc.WithUserAgent("a") 和 grpc.WithUserAgent("b"))无法区分, 有这些选项时只使用 WithClientCacheKey 指定的 key,
+// 没有指定时返回 false, 不缓存
+func clientOptionsKey(insecure bool, o clientOptions) (string, bool) {
+	parts := []string{
+		fmt.Sprintf("insecure=%t", insecure),
+		"endpoint=" + o.endpoint,
+		"timeout=" + o.timeout.String(),
+		"streamTimeout=" + o.streamTimeout.String(),
+		"reserve=" + o.deadlineReserve.String(),
+		"balancer=" + o.balancerName,
+		fmt.Sprintf("tracing=%t", o.enableTracing),
+		fmt.Sprintf("hedging=%t", o.hedgingOpts != nil),
+		"discovery=" + identity(o.discovery),
+		"metric=" + identity(o.metric),
+		"tls=" + identity(o.tlsConf),
+	}
+	opaque := len(o.unaryInts) > 0 || len(o.streamInts) > 0 || len(o.rpcOpts) > 0 || len(o.hedgingOpts) > 0
+	if o.cacheKey != "" {
+		parts = append(parts, "key="+o.cacheKey)
+	} else if opaque {
+		return "", false
+	}
+	return strings.Join(parts, "|"), true
+}
+
+func identity(v interface{}) string {
+	if v == nil {
+		return "nil"
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.UnsafePointer, reflect.Slice:
+		return fmt.Sprintf("%T@%x", v, rv.Pointer())
+	default:
+		return fmt.Sprintf("%T:%v", v, v)
+	}
+}
@@ -0,0 +1,115 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestClientManager(t *testing.T) {
+	addr := startGreeter(t, &delayGreeter{name: "lori"})
+	m := NewClientManager(WithIdleTimeout(50*time.Millisecond), WithCheckInterval(10*time.Millisecond))
+	ctx := context.Background()
+	opts := []ClientOption{
+		WithClientEndpoint("direct:///" + addr),
+		WithClientEnableTracing(false),
+		WithClientOptions(grpc.WithBlock()),
+		WithClientCacheKey("greeter"),
+	}
+
+	c1, release1, err := m.GetInsecure(ctx, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, release2, err := m.GetInsecure(ctx, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1 != c2 {
+		t.Errorf("expect same conn for same target and options")
+	}
+	c3, release3, err := m.GetInsecure(ctx, append(opts, WithClientTimeout(time.Second))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1 == c3 {
+		t.Errorf("expect different conn for different options")
+	}
+	release3()
+
+	release1()
+	time.Sleep(100 * time.Millisecond)
+	if len(m.State()["direct:///"+addr]) != 1 {
+		t.Errorf("expect in-use conn kept, got %v", m.State())
+	}
+	release2()
+	release2() // 重复 release 无影响
+	time.Sleep(100 * time.Millisecond)
+	if n := len(m.State()); n != 0 {
+		t.Errorf("expect idle conns closed, got %v", m.State())
+	}
+
+	if err = m.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = m.GetInsecure(ctx, opts...); err != ErrClientManagerClosed {
+		t.Errorf("expect %v, got %v", ErrClientManagerClosed, err)
+	}
+}
+
+func TestClientManagerOpaqueOptions(t *testing.T) {
+	addr := startGreeter(t, &delayGreeter{name: "lori"})
+	m := NewClientManager()
+	defer m.Close(context.Background())
+	ctx := context.Background()
+	endpoint := WithClientEndpoint("direct:///" + addr)
+
+	// 同一个闭包捕获不同变量, 没有指定 key 时不缓存
+	c1, release1, err := m.GetInsecure(ctx, endpoint, WithClientOptions(grpc.WithUserAgent("a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, release2, err := m.GetInsecure(ctx, endpoint, WithClientOptions(grpc.WithUserAgent("b")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1 == c2 {
+		t.Errorf("expect different conns for different dial options")
+	}
+	c3, release3, err := m.GetInsecure(ctx, endpoint, WithClientHedging(WithHedgingDelay(10*time.Millisecond)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c4, release4, err := m.GetInsecure(ctx, endpoint, WithClientHedging(WithHedgingDelay(time.Second)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c3 == c4 {
+		t.Errorf("expect different conns for different hedging options")
+	}
+	release1()
+	release2()
+	release3()
+	release4()
+	time.Sleep(50 * time.Millisecond)
+	if n := len(m.State()); n != 0 {
+		t.Errorf("expect uncached conns closed after release, got %v", m.State())
+	}
+
+	// 指定 key 时缓存
+	c5, release5, err := m.GetInsecure(ctx, endpoint, WithClientOptions(grpc.WithUserAgent("a")), WithClientCacheKey("ua-a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release5()
+	c6, release6, err := m.GetInsecure(ctx, endpoint, WithClientOptions(grpc.WithUserAgent("a")), WithClientCacheKey("ua-a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release6()
+	if c5 != c6 {
+		t.Errorf("expect same conn for same cache key")
+	}
+}
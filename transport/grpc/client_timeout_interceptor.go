@@ -5,22 +5,21 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // client超时控制中间件
-func clientTimeoutInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+// timeout: 上游没有设置超时时使用的超时时间
+// reserve: 上游设置了超时时, 给自己留出的处理时间, 下游的超时 = 剩余时间 - reserve
+func clientTimeoutInterceptor(timeout, reserve time.Duration) grpc.UnaryClientInterceptor {
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
 		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		//没有超时则忽律
-		if timeout <= 0 {
-			return invoker(ctx, method, req, reply, cc, opts...)
-		}
-		var cancel context.CancelFunc
-		//如果没设置 超时 ，才追究超时
-		if _, ok := ctx.Deadline(); !ok {
-			ctx, cancel = context.WithTimeout(ctx, timeout)
-			defer cancel()
+		ctx, cancel, err := budgetContext(ctx, timeout, reserve)
+		if err != nil {
+			return err
 		}
+		defer cancel()
 
 		/**
 		md :=metadata.New(map[string]string{"crmao":"crmaoclient"})
@@ -33,3 +32,59 @@ func clientTimeoutInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor
 		return invoker(ctx, method, req, reply, cc, opts...)
 	}
 }
+
+// budgetContext 计算下游请求的超时
+// 1. 没有 deadline 时使用 timeout
+// 2. 有 deadline 时扣除 reserve, 剩余时间不够时直接返回 DEADLINE_EXCEEDED, 不再发出请求
+func budgetContext(ctx context.Context, timeout, reserve time.Duration) (context.Context, context.CancelFunc, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		//没有超时则忽略
+		if timeout <= 0 {
+			return ctx, func() {}, nil
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		return ctx, cancel, nil
+	}
+	deadline = deadline.Add(-reserve)
+	if !time.Now().Before(deadline) {
+		return ctx, func() {}, status.Error(codes.DeadlineExceeded, "deadline budget exhausted before calling downstream")
+	}
+	if reserve <= 0 {
+		return ctx, func() {}, nil
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	return ctx, cancel, nil
+}
+
+// client stream 超时控制中间件, 超时时间是整个流的生命周期
+func clientStreamTimeoutInterceptor(timeout, reserve time.Duration) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, cancel, err := budgetContext(ctx, timeout, reserve)
+		if err != nil {
+			return nil, err
+		}
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return &timeoutClientStream{ClientStream: s, desc: desc, cancel: cancel}, nil
+	}
+}
+
+// timeoutClientStream 流结束时释放超时 context
+type timeoutClientStream struct {
+	grpc.ClientStream
+	desc   *grpc.StreamDesc
+	cancel context.CancelFunc
+}
+
+func (s *timeoutClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.desc.ServerStreams {
+		s.cancel()
+	}
+	return err
+}
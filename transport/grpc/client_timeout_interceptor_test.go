@@ -0,0 +1,84 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeClientStream struct {
+	grpc.ClientStream
+	ctx context.Context
+}
+
+func (s *fakeClientStream) RecvMsg(_ interface{}) error {
+	return io.EOF
+}
+
+func TestClientStreamTimeoutInterceptor(t *testing.T) {
+	var streamCtx context.Context
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		streamCtx = ctx
+		return &fakeClientStream{ctx: ctx}, nil
+	}
+	in := clientStreamTimeoutInterceptor(time.Minute, 0)
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	s, err := in(context.Background(), desc, nil, "/test/Stream", streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := streamCtx.Deadline(); !ok {
+		t.Fatal("expect stream context with deadline")
+	}
+	if err = s.RecvMsg(nil); err != io.EOF {
+		t.Fatalf("expect %v, got %v", io.EOF, err)
+	}
+	// 流结束后释放 context
+	if streamCtx.Err() != context.Canceled {
+		t.Errorf("expect %v, got %v", context.Canceled, streamCtx.Err())
+	}
+
+	// 已有 deadline 时不覆盖
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+	if _, err = in(ctx, desc, nil, "/test/Stream", streamer); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := streamCtx.Deadline(); !d.Equal(deadline) {
+		t.Errorf("expect %v, got %v", deadline, d)
+	}
+}
+
+func TestBudgetContext(t *testing.T) {
+	ctx, cancel, err := budgetContext(context.Background(), time.Second, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, ok := ctx.Deadline(); !ok {
+		t.Errorf("expect default timeout applied")
+	}
+
+	parent, pcancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer pcancel()
+	ctx, cancel, err = budgetContext(parent, time.Minute, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+	if remaining := time.Until(deadline); remaining > 2*time.Second {
+		t.Errorf("expect reserve subtracted, got %v", remaining)
+	}
+
+	if _, _, err = budgetContext(parent, time.Minute, 5*time.Second); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expect %v, got %v", codes.DeadlineExceeded, err)
+	}
+}
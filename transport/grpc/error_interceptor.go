@@ -0,0 +1,73 @@
+package grpc
+
+import (
+	"context"
+	"io"
+
+	"google.golang.org/grpc"
+
+	"github.com/cr-mao/lori/errors"
+)
+
+// 服务端: lori 错误码编码进 grpc status details (ErrorInfo), INTERNAL 等错误上报到 report.Default()
+// 客户端: 从 status details 还原 lori 错误码, 调用方可以直接使用 errors.IsCode / errors.ParseCoder
+
+func unaryServerErrorInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		reportError(ctx, err, info.FullMethod)
+		return resp, errors.ToGrpcError(err)
+	}
+	return resp, nil
+}
+
+func streamServerErrorInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	if err := handler(srv, ss); err != nil {
+		reportError(ss.Context(), err, info.FullMethod)
+		return errors.ToGrpcError(err)
+	}
+	return nil
+}
+
+func unaryClientErrorInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+		return errors.FromGrpcError(err)
+	}
+	return nil
+}
+
+func streamClientErrorInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+	method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	cs, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, errors.FromGrpcError(err)
+	}
+	return &errorClientStream{ClientStream: cs}, nil
+}
+
+type errorClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *errorClientStream) SendMsg(m interface{}) error {
+	return convertStreamError(s.ClientStream.SendMsg(m))
+}
+
+func (s *errorClientStream) RecvMsg(m interface{}) error {
+	return convertStreamError(s.ClientStream.RecvMsg(m))
+}
+
+func (s *errorClientStream) CloseSend() error {
+	return convertStreamError(s.ClientStream.CloseSend())
+}
+
+// io.EOF 表示流正常结束, 不能转换
+func convertStreamError(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	return errors.FromGrpcError(err)
+}
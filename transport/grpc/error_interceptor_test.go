@@ -0,0 +1,107 @@
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/example/proto"
+)
+
+const errGreeterNotFound = 120404
+
+type greeterCoder struct{}
+
+func (greeterCoder) Code() int         { return errGreeterNotFound }
+func (greeterCoder) HTTPStatus() int   { return http.StatusNotFound }
+func (greeterCoder) String() string    { return "greeter not found" }
+func (greeterCoder) Reference() string { return "" }
+
+type errorGreeter struct {
+	proto.UnimplementedGreeterServer
+}
+
+func (g *errorGreeter) SayHello(ctx context.Context, r *proto.HelloRequest) (*proto.HelloResponse, error) {
+	return nil, errors.WithCode(errGreeterNotFound, "greeter %s not found", r.Name)
+}
+
+type reasonGreeter struct {
+	proto.UnimplementedGreeterServer
+}
+
+func (g *reasonGreeter) SayHello(ctx context.Context, r *proto.HelloRequest) (*proto.HelloResponse, error) {
+	return nil, proto.ErrorGreeterNameRequired("name is empty")
+}
+
+func TestErrorReasonPropagation(t *testing.T) {
+	srv := NewServer(WithAddress("127.0.0.1:0"))
+	proto.RegisterGreeterServer(srv, &reasonGreeter{})
+	e, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	defer srv.Stop(context.Background())
+
+	conn, err := DialInsecure(context.Background(),
+		WithClientEndpoint("direct:///"+e.Host),
+		WithClientEnableTracing(false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, err = proto.NewGreeterClient(conn).SayHello(context.Background(), &proto.HelloRequest{})
+	if !proto.IsGreeterNameRequired(err) {
+		t.Fatalf("expect greeter name required, got %+v", err)
+	}
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Errorf("expect grpc code %v, got %v", codes.InvalidArgument, got)
+	}
+	info, ok := status.Convert(err).Details()[0].(*errdetails.ErrorInfo)
+	if !ok || info.Reason != "GREETER_NAME_REQUIRED" {
+		t.Errorf("unexpected details %+v", status.Convert(err).Details())
+	}
+}
+
+func TestErrorCodePropagation(t *testing.T) {
+	errors.Register(greeterCoder{})
+
+	srv := NewServer(WithAddress("127.0.0.1:0"))
+	proto.RegisterGreeterServer(srv, &errorGreeter{})
+	e, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	defer srv.Stop(context.Background())
+
+	conn, err := DialInsecure(context.Background(),
+		WithClientEndpoint("direct:///"+e.Host),
+		WithClientEnableTracing(false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, err = proto.NewGreeterClient(conn).SayHello(context.Background(), &proto.HelloRequest{Name: "lori"})
+	if !errors.IsCode(err, errGreeterNotFound) {
+		t.Fatalf("expect code %d, got %+v", errGreeterNotFound, err)
+	}
+	if got := errors.ParseCoder(err).HTTPStatus(); got != http.StatusNotFound {
+		t.Errorf("expect http status %d, got %d", http.StatusNotFound, got)
+	}
+	if got := status.Code(err); got != codes.NotFound {
+		t.Errorf("expect grpc code %v, got %v", codes.NotFound, got)
+	}
+	if err.Error() != "greeter not found" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
@@ -0,0 +1,51 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	grpcmd "google.golang.org/grpc/metadata"
+
+	"github.com/cr-mao/lori/metadata"
+	"github.com/cr-mao/lori/transport"
+)
+
+// 从请求头中提取 x-md- 开头的 metadata, 需要放在 transport 拦截器之后
+func unaryServerMetadataInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if tr, ok := transport.FromServerContext(ctx); ok {
+		ctx = metadata.Extract(ctx, tr.RequestHeader())
+	}
+	return handler(ctx, req)
+}
+
+func streamServerMetadataInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	ctx := ss.Context()
+	if tr, ok := transport.FromServerContext(ctx); ok {
+		ss = NewWrappedStream(metadata.Extract(ctx, tr.RequestHeader()), ss)
+	}
+	return handler(srv, ss)
+}
+
+// 把客户端 metadata 和需要全局透传的 metadata 写入 outgoing metadata
+func unaryClientMetadataInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(injectMetadata(ctx), method, req, reply, cc, opts...)
+}
+
+func streamClientMetadataInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+	method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(injectMetadata(ctx), desc, cc, method, opts...)
+}
+
+func injectMetadata(ctx context.Context) context.Context {
+	md, ok := grpcmd.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = grpcmd.MD{}
+	}
+	metadata.Inject(ctx, headerCarrier(md))
+	return grpcmd.NewOutgoingContext(ctx, md)
+}
@@ -0,0 +1,46 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cr-mao/lori/example/proto"
+	"github.com/cr-mao/lori/metadata"
+)
+
+type metadataGreeter struct {
+	proto.UnimplementedGreeterServer
+}
+
+func (g *metadataGreeter) SayHello(ctx context.Context, r *proto.HelloRequest) (*proto.HelloResponse, error) {
+	md, _ := metadata.FromServerContext(ctx)
+	return &proto.HelloResponse{Message: md.Get("x-md-global-tenant-id")}, nil
+}
+
+func TestMetadataPropagation(t *testing.T) {
+	srv := NewServer(WithAddress("127.0.0.1:0"))
+	proto.RegisterGreeterServer(srv, &metadataGreeter{})
+	e, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	defer srv.Stop(context.Background())
+
+	conn, err := DialInsecure(context.Background(),
+		WithClientEndpoint("direct:///"+e.Host),
+		WithClientEnableTracing(false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx := metadata.AppendToClientContext(context.Background(), "x-md-global-tenant-id", "1001")
+	resp, err := proto.NewGreeterClient(conn).SayHello(ctx, &proto.HelloRequest{Name: "lori"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message != "1001" {
+		t.Errorf("expect %v, got %v", "1001", resp.Message)
+	}
+}
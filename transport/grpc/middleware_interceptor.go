@@ -0,0 +1,29 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/cr-mao/lori/middleware"
+)
+
+// unaryMiddlewareInterceptor 把与传输层无关的中间件链适配成 grpc 拦截器, req 为请求消息
+func (s *Server) unaryMiddlewareInterceptor() grpc.UnaryServerInterceptor {
+	chain := middleware.Chain(s.ms...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return chain(middleware.Handler(handler))(ctx, req)
+	}
+}
+
+// streamMiddlewareInterceptor 流在开始时经过一次中间件链, req 为 nil
+func (s *Server) streamMiddlewareInterceptor() grpc.StreamServerInterceptor {
+	chain := middleware.Chain(s.ms...)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		h := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, handler(srv, NewWrappedStream(ctx, ss))
+		}
+		_, err := chain(h)(ss.Context(), nil)
+		return err
+	}
+}
@@ -0,0 +1,70 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cr-mao/lori/example/proto"
+	"github.com/cr-mao/lori/middleware"
+	"github.com/cr-mao/lori/middleware/recovery"
+	"github.com/cr-mao/lori/transport"
+)
+
+type panicGreeter struct {
+	proto.UnimplementedGreeterServer
+}
+
+func (g *panicGreeter) SayHello(ctx context.Context, r *proto.HelloRequest) (*proto.HelloResponse, error) {
+	if r.Name == "panic" {
+		panic("boom")
+	}
+	return &proto.HelloResponse{Message: "hello " + r.Name}, nil
+}
+
+func TestServerMiddleware(t *testing.T) {
+	var operation string
+	observe := func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				operation = tr.Operation()
+			}
+			if _, ok := req.(*proto.HelloRequest); !ok {
+				t.Errorf("expect *proto.HelloRequest, got %T", req)
+			}
+			return next(ctx, req)
+		}
+	}
+	srv := NewServer(WithAddress("127.0.0.1:0"), WithMiddleware(recovery.Recovery(), observe))
+	proto.RegisterGreeterServer(srv, &panicGreeter{})
+	e, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	defer srv.Stop(context.Background())
+
+	conn, err := DialInsecure(context.Background(),
+		WithClientEndpoint("direct:///"+e.Host),
+		WithClientEnableTracing(false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := proto.NewGreeterClient(conn)
+	resp, err := client.SayHello(context.Background(), &proto.HelloRequest{Name: "lori"})
+	if err != nil || resp.Message != "hello lori" {
+		t.Fatalf("unexpected reply %v %v", resp, err)
+	}
+	if operation != "/lori.example.proto.Greeter/SayHello" {
+		t.Errorf("unexpected operation %s", operation)
+	}
+	// panic 被中间件链中的 recovery 恢复, 转成 INTERNAL
+	_, err = client.SayHello(context.Background(), &proto.HelloRequest{Name: "panic"})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("expect INTERNAL, got %v", err)
+	}
+}
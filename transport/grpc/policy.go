@@ -0,0 +1,269 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/log"
+)
+
+const (
+	// ErrRequestTooLarge 请求消息超过 MaxRequestSize, 返回 413, grpc 为 RESOURCE_EXHAUSTED
+	ErrRequestTooLarge = 100413
+	// ErrConcurrencyLimited 并发请求数超过 MaxConcurrency, 返回 429, grpc 为 RESOURCE_EXHAUSTED
+	ErrConcurrencyLimited = 100005
+)
+
+func init() {
+	errors.Register(errors.NewReasonCoder(ErrRequestTooLarge, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE", "Request too large", ""))
+	errors.Register(errors.NewReasonCoder(ErrConcurrencyLimited, http.StatusTooManyRequests, "CONCURRENCY_LIMITED", "Too many concurrent requests, please try again later", ""))
+}
+
+// MethodPolicy 方法级别的策略
+type MethodPolicy struct {
+	Timeout time.Duration // 超时时间, 0 使用 server 的默认超时, 流方法为整个流的生命周期
+	// MaxRequestSize 请求消息最大字节数, 0 不限制, 流方法限制收到的每一条消息.
+	// 检查的是解码后的消息大小, 消息已经完整读入内存, 限制单条消息占用的内存需要同时配置
+	// WithGrpcOpts(grpc.MaxRecvMsgSize(n)), 它对所有方法生效, 默认 4MB
+	MaxRequestSize int
+	MaxConcurrency int  // 最大并发数, 0 不限制, 流方法限制同时打开的流
+	LogPayload     bool // 是否打印请求和响应, 流方法打印收发的每一条消息
+}
+
+// methodPolicyConfig 配置文件格式, 超时为 go duration 字符串
+type methodPolicyConfig struct {
+	Timeout        string `json:"timeout"`
+	MaxRequestSize int    `json:"max_request_size"`
+	MaxConcurrency int    `json:"max_concurrency"`
+	LogPayload     bool   `json:"log_payload"`
+}
+
+// ParsePolicies 解析 json 格式的策略配置, key 为方法全名或者通配, 例如:
+//
+//	{
+//	  "*": {"timeout": "2s"},
+//	  "/helloworld.Greeter/*": {"timeout": "5s", "max_concurrency": 100},
+//	  "/export.Exporter/Batch": {"timeout": "60s", "max_request_size": 4194304, "log_payload": true}
+//	}
+func ParsePolicies(data []byte) (map[string]MethodPolicy, error) {
+	var conf map[string]methodPolicyConfig
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return nil, errors.Wrap(err, "parse grpc method policies")
+	}
+	policies := make(map[string]MethodPolicy, len(conf))
+	for pattern, c := range conf {
+		p := MethodPolicy{
+			MaxRequestSize: c.MaxRequestSize,
+			MaxConcurrency: c.MaxConcurrency,
+			LogPayload:     c.LogPayload,
+		}
+		if c.Timeout != "" {
+			d, err := time.ParseDuration(c.Timeout)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse timeout of %s", pattern)
+			}
+			p.Timeout = d
+		}
+		policies[pattern] = p
+	}
+	return policies, nil
+}
+
+// compiledPolicy 带并发控制的策略
+type compiledPolicy struct {
+	MethodPolicy
+	sem chan struct{}
+}
+
+func (p *compiledPolicy) acquire() bool {
+	if p.sem == nil {
+		return true
+	}
+	select {
+	case p.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *compiledPolicy) release() {
+	if p.sem != nil {
+		<-p.sem
+	}
+}
+
+type policySet struct {
+	exact    map[string]*compiledPolicy
+	prefixes []string // 按长度倒序, 最长匹配
+	wildcard map[string]*compiledPolicy
+	fallback *compiledPolicy // "*"
+}
+
+// PolicyTable 方法策略表, 匹配顺序: 方法全名 > 最长的服务通配 (/pkg.Service/*) > "*",
+// 可以在运行时通过 Update 整体替换
+type PolicyTable struct {
+	v atomic.Value // *policySet
+}
+
+// NewPolicyTable 创建策略表
+func NewPolicyTable(policies map[string]MethodPolicy) *PolicyTable {
+	t := &PolicyTable{}
+	t.Update(policies)
+	return t
+}
+
+// Update 替换整个策略表, 正在执行的请求仍然使用旧的并发计数
+func (t *PolicyTable) Update(policies map[string]MethodPolicy) {
+	set := &policySet{
+		exact:    make(map[string]*compiledPolicy),
+		wildcard: make(map[string]*compiledPolicy),
+	}
+	for pattern, p := range policies {
+		cp := &compiledPolicy{MethodPolicy: p}
+		if p.MaxConcurrency > 0 {
+			cp.sem = make(chan struct{}, p.MaxConcurrency)
+		}
+		switch {
+		case pattern == "*":
+			set.fallback = cp
+		case strings.HasSuffix(pattern, "/*"):
+			prefix := strings.TrimSuffix(pattern, "*")
+			set.wildcard[prefix] = cp
+			set.prefixes = append(set.prefixes, prefix)
+		default:
+			set.exact[pattern] = cp
+		}
+	}
+	// 长的前缀优先
+	sort.Slice(set.prefixes, func(i, j int) bool { return len(set.prefixes[i]) > len(set.prefixes[j]) })
+	t.v.Store(set)
+}
+
+// Load 从 json 配置替换策略表
+func (t *PolicyTable) Load(data []byte) error {
+	policies, err := ParsePolicies(data)
+	if err != nil {
+		return err
+	}
+	t.Update(policies)
+	return nil
+}
+
+// Match 返回方法对应的策略
+func (t *PolicyTable) Match(method string) (MethodPolicy, bool) {
+	if p := t.match(method); p != nil {
+		return p.MethodPolicy, true
+	}
+	return MethodPolicy{}, false
+}
+
+func (t *PolicyTable) match(method string) *compiledPolicy {
+	if t == nil {
+		return nil
+	}
+	set, _ := t.v.Load().(*policySet)
+	if set == nil {
+		return nil
+	}
+	if p, ok := set.exact[method]; ok {
+		return p
+	}
+	for _, prefix := range set.prefixes {
+		if strings.HasPrefix(method, prefix) {
+			return set.wildcard[prefix]
+		}
+	}
+	return set.fallback
+}
+
+// timeout 方法的超时时间, 没有配置时返回 def
+func (t *PolicyTable) timeout(method string, def time.Duration) time.Duration {
+	if p := t.match(method); p != nil && p.Timeout > 0 {
+		return p.Timeout
+	}
+	return def
+}
+
+// unaryPolicyInterceptor 请求大小、并发数限制, 以及请求响应日志
+func (s *Server) unaryPolicyInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		p := s.policies.match(info.FullMethod)
+		if p == nil {
+			return handler(ctx, req)
+		}
+		if p.MaxRequestSize > 0 {
+			if m, ok := req.(proto.Message); ok && proto.Size(m) > p.MaxRequestSize {
+				return nil, errors.WithCode(ErrRequestTooLarge, "%s request size %d exceeds limit %d", info.FullMethod, proto.Size(m), p.MaxRequestSize)
+			}
+		}
+		if !p.acquire() {
+			return nil, errors.WithCode(ErrConcurrencyLimited, "too many concurrent requests for %s", info.FullMethod)
+		}
+		defer p.release()
+		start := time.Now()
+		reply, err := handler(ctx, req)
+		if p.LogPayload {
+			log.Infof("grpc payload:method:%s,duration:%v,request:%v,reply:%v,error:%v",
+				info.FullMethod, time.Since(start), req, reply, err)
+		}
+		return reply, err
+	}
+}
+
+// streamPolicyInterceptor 流的并发数限制, 每条消息的大小限制和日志
+func (s *Server) streamPolicyInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		p := s.policies.match(info.FullMethod)
+		if p == nil {
+			return handler(srv, ss)
+		}
+		if !p.acquire() {
+			return errors.WithCode(ErrConcurrencyLimited, "too many concurrent streams for %s", info.FullMethod)
+		}
+		defer p.release()
+		if p.MaxRequestSize > 0 || p.LogPayload {
+			ss = &policyStream{ServerStream: ss, policy: p, method: info.FullMethod}
+		}
+		return handler(srv, ss)
+	}
+}
+
+// policyStream 对流中的每条消息检查大小和打印日志
+type policyStream struct {
+	grpc.ServerStream
+	policy *compiledPolicy
+	method string
+}
+
+func (s *policyStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.policy.MaxRequestSize > 0 {
+		if msg, ok := m.(proto.Message); ok && proto.Size(msg) > s.policy.MaxRequestSize {
+			return errors.WithCode(ErrRequestTooLarge, "%s request size %d exceeds limit %d", s.method, proto.Size(msg), s.policy.MaxRequestSize)
+		}
+	}
+	if s.policy.LogPayload {
+		log.Infof("grpc stream payload:method:%s,recv:%v", s.method, m)
+	}
+	return nil
+}
+
+func (s *policyStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if s.policy.LogPayload {
+		log.Infof("grpc stream payload:method:%s,send:%v,error:%v", s.method, m, err)
+	}
+	return err
+}
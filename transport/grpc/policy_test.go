@@ -0,0 +1,116 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/example/proto"
+)
+
+func TestPolicyTable_Match(t *testing.T) {
+	table := NewPolicyTable(nil)
+	err := table.Load([]byte(`{
+		"*": {"timeout": "2s"},
+		"/helloworld.Greeter/*": {"timeout": "5s"},
+		"/helloworld.Greeter/Export": {"timeout": "60s", "log_payload": true}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]time.Duration{
+		"/helloworld.Greeter/Export":   60 * time.Second,
+		"/helloworld.Greeter/SayHello": 5 * time.Second,
+		"/other.Service/Call":          2 * time.Second,
+	}
+	for method, expect := range tests {
+		p, ok := table.Match(method)
+		if !ok || p.Timeout != expect {
+			t.Errorf("%s: expect %v, got %v", method, expect, p.Timeout)
+		}
+	}
+
+	table.Update(map[string]MethodPolicy{"/helloworld.Greeter/SayHello": {Timeout: time.Second}})
+	if _, ok := table.Match("/other.Service/Call"); ok {
+		t.Errorf("expect policies replaced")
+	}
+}
+
+func TestPolicyInterceptor(t *testing.T) {
+	s := &Server{policies: NewPolicyTable(map[string]MethodPolicy{
+		"/lori.example.proto.Greeter/*": {MaxRequestSize: 4, MaxConcurrency: 1},
+	})}
+	in := s.unaryPolicyInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/lori.example.proto.Greeter/SayHello"}
+
+	_, err := in(context.Background(), &proto.HelloRequest{Name: "too long name"}, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+	if status.Code(err) != codes.ResourceExhausted || !errors.IsCode(err, ErrRequestTooLarge) {
+		t.Errorf("expect %v, got %v", codes.ResourceExhausted, err)
+	}
+
+	_, err = in(context.Background(), &proto.HelloRequest{Name: "a"}, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			// 并发数为 1, 嵌套调用会被拒绝
+			_, err := in(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+			return nil, err
+		})
+	if status.Code(err) != codes.ResourceExhausted || !errors.IsCode(err, ErrConcurrencyLimited) {
+		t.Errorf("expect %v, got %v", codes.ResourceExhausted, err)
+	}
+}
+
+// recvStream 依次返回 msgs 中的消息
+type recvStream struct {
+	grpc.ServerStream
+	msgs []*proto.HelloRequest
+	sent int
+}
+
+func (s *recvStream) Context() context.Context { return context.Background() }
+
+func (s *recvStream) RecvMsg(m interface{}) error {
+	if len(s.msgs) == 0 {
+		return io.EOF
+	}
+	m.(*proto.HelloRequest).Name = s.msgs[0].Name
+	s.msgs = s.msgs[1:]
+	return nil
+}
+
+func (s *recvStream) SendMsg(m interface{}) error {
+	s.sent++
+	return nil
+}
+
+func TestStreamPolicyInterceptor(t *testing.T) {
+	s := &Server{policies: NewPolicyTable(map[string]MethodPolicy{
+		"/lori.example.proto.Greeter/*": {MaxRequestSize: 4, LogPayload: true},
+	})}
+	in := s.streamPolicyInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/lori.example.proto.Greeter/Chat", IsClientStream: true}
+
+	ss := &recvStream{msgs: []*proto.HelloRequest{{Name: "a"}, {Name: "too long name"}}}
+	var received int
+	err := in(nil, ss, info, func(srv interface{}, stream grpc.ServerStream) error {
+		for {
+			var req proto.HelloRequest
+			if err := stream.RecvMsg(&req); err != nil {
+				return err
+			}
+			received++
+			if err := stream.SendMsg(&proto.HelloResponse{Message: req.Name}); err != nil {
+				return err
+			}
+		}
+	})
+	if status.Code(err) != codes.ResourceExhausted || !errors.IsCode(err, ErrRequestTooLarge) || received != 1 || ss.sent != 1 {
+		t.Errorf("expect second message rejected, got %v after %d messages", err, received)
+	}
+}
@@ -19,6 +19,8 @@ import (
 	"github.com/cr-mao/lori/internal/host"
 	"github.com/cr-mao/lori/log"
 	"github.com/cr-mao/lori/metric"
+	"github.com/cr-mao/lori/middleware"
+	"github.com/cr-mao/lori/ratelimit"
 	"github.com/cr-mao/lori/transport"
 )
 
@@ -37,12 +39,19 @@ type Server struct {
 	grpcOpts   []grpc.ServerOption            //
 	lis        net.Listener
 	timeout    time.Duration
-	health     *health.Server // 健康检测server
+	// 流的超时时间(整个流的生命周期), 默认0不限制
+	streamTimeout time.Duration
+	health        *health.Server // 健康检测server
 	//metadata      *apimd.Server
-	endpoint      *url.URL          // url
-	metric        metric.GrpcMetric //metric 接口，可以传可不传
-	enableTracing bool              //是否开启链路追踪
-	err           error
+	endpoint       *url.URL                // url
+	metric         metric.GrpcMetric       //metric 接口，可以传可不传
+	enableTracing  bool                    //是否开启链路追踪
+	enableValidate bool                    //是否校验请求参数
+	limiter        ratelimit.Limiter       // 全局限流器, 比如 bbr
+	limitRules     ratelimit.Rules         // 方法级别的静态限流
+	policies       *PolicyTable            // 方法级别的超时、请求大小、并发、日志策略
+	ms             []middleware.Middleware // 与传输层无关的中间件
+	err            error
 }
 
 // NewServer creates a gRPC server by options.
@@ -58,21 +67,46 @@ func NewServer(opts ...ServerOption) *Server {
 		o(srv)
 	}
 	unaryInts := []grpc.UnaryServerInterceptor{
-		unaryCrashInterceptor,        //防止panic crash 中间件
-		srv.unaryServerInterceptor(), //metadata 方便获取， 请求超时控制中间件
-		unaryErrorLogInterceptor,     //发生错误的日志
+		unaryCrashInterceptor,          //防止panic crash 中间件
+		unaryServerErrorInterceptor,    //lori 错误码编码进 grpc status details
+		srv.unaryServerInterceptor(),   //metadata 方便获取， 请求超时控制中间件
+		unaryServerMetadataInterceptor, //x-md- 开头的请求头放入 metadata.FromServerContext
 	}
+	streamInts := []grpc.StreamServerInterceptor{
+		streamCrashInterceptor,
+		streamServerErrorInterceptor,
+		srv.streamServerInterceptor(),
+		streamServerMetadataInterceptor,
+	}
+	// 限流尽量靠前，被拒绝的请求不再往下走，也不打错误日志
+	if srv.limiter != nil || len(srv.limitRules) > 0 {
+		unaryInts = append(unaryInts, UnaryLimitInterceptor(srv.limiter, srv.limitRules))
+		streamInts = append(streamInts, StreamLimitInterceptor(srv.limiter, srv.limitRules))
+	}
+	if srv.policies != nil {
+		unaryInts = append(unaryInts, srv.unaryPolicyInterceptor())
+		streamInts = append(streamInts, srv.streamPolicyInterceptor())
+	}
+	unaryInts = append(unaryInts, unaryErrorLogInterceptor) //发生错误的日志
+	streamInts = append(streamInts, streamErrorLogInterceptor)
 	if srv.enableTracing {
 		unaryInts = append(unaryInts, otelgrpc.UnaryServerInterceptor())
+		streamInts = append(streamInts, otelgrpc.StreamServerInterceptor())
 	}
 
 	if srv.metric != nil {
 		unaryInts = append(unaryInts, srv.metric.GrpcMetricInterceptors()...)
+		streamInts = append(streamInts, srv.metric.GrpcMetricStreamInterceptors()...)
 	}
 
-	streamInts := []grpc.StreamServerInterceptor{
-		streamCrashInterceptor,
-		srv.streamServerInterceptor(),
+	if srv.enableValidate {
+		unaryInts = append(unaryInts, UnaryValidateInterceptor)
+		streamInts = append(streamInts, StreamValidateInterceptor)
+	}
+
+	if len(srv.ms) > 0 {
+		unaryInts = append(unaryInts, srv.unaryMiddlewareInterceptor())
+		streamInts = append(streamInts, srv.streamMiddlewareInterceptor())
 	}
 
 	if len(srv.unaryInts) > 0 {
@@ -107,6 +141,16 @@ func NewServer(opts ...ServerOption) *Server {
 	return srv
 }
 
+// Policies 返回方法策略表, 没有设置时为 nil
+func (s *Server) Policies() *PolicyTable {
+	return s.policies
+}
+
+// Health 健康检查服务, 单端口复用(transport/mux)时由 mux.Server 控制状态
+func (s *Server) Health() *health.Server {
+	return s.health
+}
+
 // Endpoint return a real address to registry endpoint.
 // examples:
 //
@@ -176,6 +220,27 @@ func WithAddress(address string) ServerOption {
 	}
 }
 
+// WithTimeout 一元请求的超时时间, 默认 2s
+func WithTimeout(timeout time.Duration) ServerOption {
+	return func(o *Server) {
+		o.timeout = timeout
+	}
+}
+
+// WithPolicies 方法级别的策略表, 策略表可以在运行时通过 PolicyTable.Update 替换
+func WithPolicies(policies *PolicyTable) ServerOption {
+	return func(o *Server) {
+		o.policies = policies
+	}
+}
+
+// WithStreamTimeout 流的超时时间, 是整个流的生命周期, 默认不限制
+func WithStreamTimeout(timeout time.Duration) ServerOption {
+	return func(o *Server) {
+		o.streamTimeout = timeout
+	}
+}
+
 // 是否开启链路追踪
 func WithEnableTrace(enableTraceing bool) ServerOption {
 	return func(o *Server) {
@@ -183,6 +248,13 @@ func WithEnableTrace(enableTraceing bool) ServerOption {
 	}
 }
 
+// WithEnableValidate 是否校验请求参数, 请求消息实现了 ValidateAll/Validate (protoc-gen-validate) 时调用
+func WithEnableValidate(enable bool) ServerOption {
+	return func(o *Server) {
+		o.enableValidate = enable
+	}
+}
+
 // Listener with server lis
 func WithListener(lis net.Listener) ServerOption {
 	return func(s *Server) {
@@ -190,6 +262,20 @@ func WithListener(lis net.Listener) ServerOption {
 	}
 }
 
+// WithLimiter 设置全局限流器, 比如 bbr.NewLimiter(), 被拒绝的请求返回 RESOURCE_EXHAUSTED
+func WithLimiter(limiter ratelimit.Limiter) ServerOption {
+	return func(s *Server) {
+		s.limiter = limiter
+	}
+}
+
+// WithLimitRules 设置方法级别的静态限流, key 为 grpc 方法全名
+func WithLimitRules(rules ratelimit.Rules) ServerOption {
+	return func(s *Server) {
+		s.limitRules = rules
+	}
+}
+
 // WithUnaryInterceptor returns a ServerOption that sets the UnaryServerInterceptor for the server.
 func WithUnaryInterceptor(in ...grpc.UnaryServerInterceptor) ServerOption {
 	return func(s *Server) {
@@ -204,6 +290,14 @@ func WithStreamInterceptor(in ...grpc.StreamServerInterceptor) ServerOption {
 	}
 }
 
+// WithMiddleware 与传输层无关的中间件, 在内置拦截器之后、用户拦截器之前执行,
+// 可以和 http.WithMiddleware 共用同一条链
+func WithMiddleware(m ...middleware.Middleware) ServerOption {
+	return func(s *Server) {
+		s.ms = m
+	}
+}
+
 // WithGrpcOpts with grpc options.
 func WithGrpcOpts(opts ...grpc.ServerOption) ServerOption {
 	return func(s *Server) {
@@ -5,16 +5,23 @@ import (
 	"runtime/debug"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
+	"github.com/cr-mao/lori/errors/report"
 	"github.com/cr-mao/lori/log"
 )
 
-// 防止panic crash 中间件
+// 防止panic crash 中间件, panic 上报到 report.Default(), 返回 INTERNAL
 
-func streamCrashInterceptor(svr interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo,
+func streamCrashInterceptor(svr interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
 	handler grpc.StreamHandler) (err error) {
 	defer handleCrash(func(r interface{}) {
-		log.Errorf("%+v\n \n %s", r, debug.Stack())
+		stack := debug.Stack()
+		log.Errorf("recovery method: %s, message: %+v\n \n %s", info.FullMethod, r, stack)
+		reportPanic(stream.Context(), r, stack, info.FullMethod)
+		err = status.Error(codes.Internal, "internal error")
 	})
 
 	return handler(svr, stream)
@@ -23,7 +30,10 @@ func streamCrashInterceptor(svr interface{}, stream grpc.ServerStream, _ *grpc.S
 func unaryCrashInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
 	handler grpc.UnaryHandler) (resp interface{}, err error) {
 	defer handleCrash(func(r interface{}) {
-		log.Errorf("recovery method: %s, message: %+v\n \n %s", info.FullMethod, r, debug.Stack())
+		stack := debug.Stack()
+		log.Errorf("recovery method: %s, message: %+v\n \n %s", info.FullMethod, r, stack)
+		reportPanic(ctx, r, stack, info.FullMethod)
+		err = status.Error(codes.Internal, "internal error")
 	})
 	return handler(ctx, req)
 }
@@ -33,3 +43,17 @@ func handleCrash(handler func(interface{})) {
 		handler(r)
 	}
 }
+
+func reportPanic(ctx context.Context, r interface{}, stack []byte, method string) {
+	md, _ := grpcmd.FromIncomingContext(ctx)
+	report.Panic(ctx, r, stack, method, md)
+}
+
+// reportError INTERNAL 等服务端错误上报到 report.Default()
+func reportError(ctx context.Context, err error, method string) {
+	switch status.Code(err) {
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		md, _ := grpcmd.FromIncomingContext(ctx)
+		report.Error(ctx, err, method, md)
+	}
+}
@@ -0,0 +1,37 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cr-mao/lori/errors/report"
+)
+
+type recordReporter struct {
+	reports []*report.Report
+}
+
+func (r *recordReporter) Report(_ context.Context, rep *report.Report) {
+	r.reports = append(r.reports, rep)
+}
+
+func TestUnaryCrashInterceptor(t *testing.T) {
+	rec := &recordReporter{}
+	report.SetDefault(rec)
+	defer report.SetDefault(nil)
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/lori.example.proto.Greeter/SayHello"}
+	_, err := unaryCrashInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("expect %v, got %v", codes.Internal, err)
+	}
+	if len(rec.reports) != 1 || rec.reports[0].Kind != report.KindPanic || rec.reports[0].Operation != info.FullMethod {
+		t.Errorf("unexpected reports %+v", rec.reports)
+	}
+}
@@ -2,13 +2,20 @@ package grpc
 
 import (
 	"context"
+	"fmt"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/status"
 
+	"github.com/cr-mao/lori/errors"
 	"github.com/cr-mao/lori/log"
 )
 
+// logError 错误日志带上 errors.WithFields 附加的字段
+func logError(ctx context.Context, err error, format string, a ...interface{}) {
+	log.Context(ctx).Errorw(append([]interface{}{log.DefaultMessageKey, fmt.Sprintf(format, a...)}, errors.Fields(err)...)...)
+}
+
 func unaryErrorLogInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
 	handler grpc.UnaryHandler) (interface{}, error) {
 	resp, err := handler(ctx, req)
@@ -17,10 +24,26 @@ func unaryErrorLogInterceptor(ctx context.Context, req interface{}, info *grpc.U
 	}
 	if gstatus, ok := status.FromError(err); ok {
 		errLog := "grpc error:method:%s, code:%v,message:%v"
-		log.Errorf(errLog, info.FullMethod, gstatus.Code(), err.Error())
+		logError(ctx, err, errLog, info.FullMethod, gstatus.Code(), err.Error())
 	} else {
 		errLog := "not grpc error:method:%s,message:%v"
-		log.Errorf(errLog, info.FullMethod, err.Error())
+		logError(ctx, err, errLog, info.FullMethod, err.Error())
 	}
 	return resp, err
 }
+
+func streamErrorLogInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	err := handler(srv, ss)
+	if err == nil {
+		return nil
+	}
+	if gstatus, ok := status.FromError(err); ok {
+		errLog := "grpc stream error:method:%s, code:%v,message:%v"
+		logError(ss.Context(), err, errLog, info.FullMethod, gstatus.Code(), err.Error())
+	} else {
+		errLog := "not grpc stream error:method:%s,message:%v"
+		logError(ss.Context(), err, errLog, info.FullMethod, err.Error())
+	}
+	return err
+}
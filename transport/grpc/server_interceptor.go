@@ -2,9 +2,12 @@ package grpc
 
 import (
 	"context"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/cr-mao/lori/transport"
 )
@@ -26,8 +29,13 @@ func (s *Server) unaryServerInterceptor() grpc.UnaryServerInterceptor {
 			tr.endpoint = s.endpoint.String()
 		}
 		ctx = transport.NewServerContext(ctx, tr)
-		if s.timeout > 0 {
-			ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		// 调用方的 deadline 已经过期, 不再执行handler
+		if deadlineExpired(ctx) {
+			return nil, status.Error(codes.DeadlineExceeded, "deadline exceeded before handling request")
+		}
+		// 调用方的剩余时间比超时时间短时, 以调用方为准
+		if timeout := s.policies.timeout(info.FullMethod, s.timeout); timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
 			defer cancel()
 		}
 		reply, err := handler(ctx, req)
@@ -39,6 +47,12 @@ func (s *Server) unaryServerInterceptor() grpc.UnaryServerInterceptor {
 	}
 }
 
+// deadlineExpired 请求到达时 deadline 是否已经过期
+func deadlineExpired(ctx context.Context) bool {
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
+}
+
 // wrappedStream is rewrite grpc stream's context
 type wrappedStream struct {
 	grpc.ServerStream
@@ -62,12 +76,24 @@ func (s *Server) streamServerInterceptor() grpc.StreamServerInterceptor {
 		ctx := ss.Context()
 		md, _ := grpcmd.FromIncomingContext(ctx)
 		replyHeader := grpcmd.MD{}
-		ctx = transport.NewServerContext(ctx, &Transport{
-			endpoint:    s.endpoint.String(),
+		tr := &Transport{
 			operation:   info.FullMethod,
 			reqHeader:   headerCarrier(md),
 			replyHeader: headerCarrier(replyHeader),
-		})
+		}
+		if s.endpoint != nil {
+			tr.endpoint = s.endpoint.String()
+		}
+		ctx = transport.NewServerContext(ctx, tr)
+		if deadlineExpired(ctx) {
+			return status.Error(codes.DeadlineExceeded, "deadline exceeded before handling stream")
+		}
+		// 流的超时是整个流的生命周期, 默认不限制
+		if timeout := s.policies.timeout(info.FullMethod, s.streamTimeout); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
 		ws := NewWrappedStream(ctx, ss)
 		err := handler(srv, ws)
 		if len(replyHeader) > 0 {
@@ -0,0 +1,52 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/ratelimit"
+)
+
+// UnaryLimitInterceptor 限流拦截器, 先匹配方法级别的静态限流, 再经过全局限流器 (如 bbr),
+// 被拒绝的请求直接返回 ratelimit.ErrRateLimited (RESOURCE_EXHAUSTED), 不会进入业务handler
+func UnaryLimitInterceptor(limiter ratelimit.Limiter, rules ratelimit.Rules) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (reply interface{}, err error) {
+		done, err := ratelimit.Allow(rules.Match(info.FullMethod), limiter)
+		if err != nil {
+			return nil, errors.WrapC(err, ratelimit.ErrRateLimited, "%s rate limited", info.FullMethod)
+		}
+		// handler panic 时也要回调 done, 否则在途请求数不会减少
+		finished := false
+		defer func() {
+			if !finished {
+				done(ratelimit.DoneInfo{Err: ratelimit.ErrPanic})
+			}
+		}()
+		reply, err = handler(ctx, req)
+		finished = true
+		done(ratelimit.DoneInfo{Err: err})
+		return reply, err
+	}
+}
+
+// StreamLimitInterceptor 流式限流拦截器, 在建立流时判断
+func StreamLimitInterceptor(limiter ratelimit.Limiter, rules ratelimit.Rules) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		done, err := ratelimit.Allow(rules.Match(info.FullMethod), limiter)
+		if err != nil {
+			return errors.WrapC(err, ratelimit.ErrRateLimited, "%s rate limited", info.FullMethod)
+		}
+		finished := false
+		defer func() {
+			if !finished {
+				done(ratelimit.DoneInfo{Err: ratelimit.ErrPanic})
+			}
+		}()
+		err = handler(srv, ss)
+		finished = true
+		done(ratelimit.DoneInfo{Err: err})
+		return err
+	}
+}
@@ -0,0 +1,55 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/ratelimit"
+	"github.com/cr-mao/lori/ratelimit/bucket"
+)
+
+type panicLimiter struct {
+	inFlight int
+	errs     []error
+}
+
+func (l *panicLimiter) Allow() (ratelimit.DoneFunc, error) {
+	l.inFlight++
+	return func(di ratelimit.DoneInfo) {
+		l.inFlight--
+		l.errs = append(l.errs, di.Err)
+	}, nil
+}
+
+func TestUnaryLimitInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/lori.example.proto.Greeter/SayHello"}
+	limiter := &panicLimiter{}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expect panic propagated")
+			}
+		}()
+		_, _ = UnaryLimitInterceptor(limiter, nil)(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	}()
+	if limiter.inFlight != 0 || len(limiter.errs) != 1 || limiter.errs[0] != ratelimit.ErrPanic {
+		t.Errorf("expect done called on panic, got %d %v", limiter.inFlight, limiter.errs)
+	}
+
+	b := bucket.New(0.001, 1)
+	_, _ = b.Allow()
+	rules := ratelimit.Rules{info.FullMethod: b}
+	_, err := UnaryLimitInterceptor(nil, rules)(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	if !errors.IsCode(err, ratelimit.ErrRateLimited) || status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expect rate limited, got %v", err)
+	}
+}
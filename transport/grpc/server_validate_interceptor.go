@@ -0,0 +1,34 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/cr-mao/lori/middleware/validate"
+)
+
+// UnaryValidateInterceptor 请求消息实现了 ValidateAll/Validate (protoc-gen-validate) 时先校验,
+// 失败返回 validate.ErrValidation, 字段错误放在 status details 的 BadRequest 中, 不会进入业务handler
+func UnaryValidateInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := validate.Validate(req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamValidateInterceptor 流式校验拦截器, 每次 RecvMsg 收到的消息都会校验
+func StreamValidateInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &validateStream{ServerStream: ss})
+}
+
+type validateStream struct {
+	grpc.ServerStream
+}
+
+func (s *validateStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return validate.Validate(m)
+}
@@ -0,0 +1,63 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/cr-mao/lori/errors"
+)
+
+type nameField struct{}
+
+func (nameField) Field() string  { return "Name" }
+func (nameField) Reason() string { return "value length must be at least 1 runes" }
+func (nameField) Cause() error   { return nil }
+func (nameField) Error() string {
+	return "invalid HelloRequest.Name: value length must be at least 1 runes"
+}
+
+type validateRequest struct {
+	name string
+}
+
+func (r *validateRequest) Validate() error {
+	if r.name == "" {
+		return nameField{}
+	}
+	return nil
+}
+
+func TestUnaryValidateInterceptor(t *testing.T) {
+	var called bool
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return "ok", nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/lori.example.proto.Greeter/SayHello"}
+
+	_, err := UnaryValidateInterceptor(context.Background(), &validateRequest{}, info, handler)
+	if called {
+		t.Fatal("handler should not be called")
+	}
+	st := errors.ToGrpcStatus(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("expect INVALID_ARGUMENT, got %v", st)
+	}
+	var br *errdetails.BadRequest
+	for _, d := range st.Details() {
+		if v, ok := d.(*errdetails.BadRequest); ok {
+			br = v
+		}
+	}
+	if br == nil || len(br.FieldViolations) != 1 || br.FieldViolations[0].Field != "Name" {
+		t.Fatalf("expect BadRequest details, got %+v", st.Details())
+	}
+
+	if reply, err := UnaryValidateInterceptor(context.Background(), &validateRequest{name: "lori"}, info, handler); err != nil || reply != "ok" {
+		t.Errorf("unexpected reply %v %v", reply, err)
+	}
+}
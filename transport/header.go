@@ -0,0 +1,35 @@
+package transport
+
+import "net/http"
+
+// HTTPHeaderCarrier 把 http.Header 包装成 Header, transport/http 和它的中间件共用
+type HTTPHeaderCarrier http.Header
+
+// Get returns the value associated with the passed key.
+func (hc HTTPHeaderCarrier) Get(key string) string {
+	return http.Header(hc).Get(key)
+}
+
+// Set stores the key-value pair.
+func (hc HTTPHeaderCarrier) Set(key string, value string) {
+	http.Header(hc).Set(key, value)
+}
+
+// Add append value to key-values pair.
+func (hc HTTPHeaderCarrier) Add(key string, value string) {
+	http.Header(hc).Add(key, value)
+}
+
+// Keys lists the keys stored in this carrier.
+func (hc HTTPHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(hc))
+	for k := range http.Header(hc) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Values returns a slice of values associated with the passed key.
+func (hc HTTPHeaderCarrier) Values(key string) []string {
+	return http.Header(hc).Values(key)
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/middleware/validate"
+)
+
+// validateKey WithEnableValidate 开启时放入 gin.Context, 生成的路由据此校验 proto 请求
+const validateKey = "lori/validate"
+
+// Bind 按请求方法和 Content-Type 绑定到结构体, 并按 binding tag 校验, 比如 `json:"name" binding:"required,max=32"`;
+// 解码失败返回 ErrBind, 校验失败返回 validate.ErrValidation, 字段错误通过 errors.Violations 获取,
+// 字段名优先使用 json tag, 其次是 form tag
+func Bind(c *gin.Context, obj interface{}) error {
+	return BindWith(c, obj, binding.Default(c.Request.Method, c.ContentType()))
+}
+
+// BindWith 使用指定的 binding 绑定并校验, 比如 binding.Query, 见 Bind
+func BindWith(c *gin.Context, obj interface{}, b binding.Binding) error {
+	err := c.ShouldBindWith(obj, b)
+	if err == nil {
+		return nil
+	}
+	var ves validator.ValidationErrors
+	if !errors.As(err, &ves) {
+		return errors.WrapC(err, ErrBind, "bind request")
+	}
+	violations := make([]errors.FieldViolation, 0, len(ves))
+	for _, fe := range ves {
+		violations = append(violations, errors.FieldViolation{
+			Field:       fieldPath(reflect.TypeOf(obj), fe.StructNamespace()),
+			Description: description(fe),
+		})
+	}
+	return errors.WithViolations(errors.WrapC(err, validate.ErrValidation, "%s", err.Error()), violations...)
+}
+
+// Validate 开启 WithEnableValidate 时校验 protoc-gen-validate 生成的请求, protoc-gen-lori-http 生成的路由使用
+func Validate(c *gin.Context, msg interface{}) error {
+	if !c.GetBool(validateKey) {
+		return nil
+	}
+	return validate.Validate(msg)
+}
+
+func description(fe validator.FieldError) string {
+	tag := fe.Tag()
+	if p := fe.Param(); p != "" {
+		tag += "=" + p
+	}
+	return "validation failed on " + tag
+}
+
+// fieldPath 结构体字段路径(User.Profile.Name, User.Items[0].ID)转换为 json/form 名(profile.name, items[0].id)
+func fieldPath(t reflect.Type, namespace string) string {
+	parts := strings.Split(namespace, ".")
+	// 第一段是结构体类型名
+	if len(parts) > 1 {
+		parts = parts[1:]
+	}
+	for i, part := range parts {
+		name, index := part, ""
+		if j := strings.IndexByte(part, '['); j >= 0 {
+			name, index = part[:j], part[j:]
+		}
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			t = nil
+			continue
+		}
+		sf, ok := t.FieldByName(name)
+		if !ok {
+			t = nil
+			continue
+		}
+		parts[i] = tagName(sf) + index
+		t = sf.Type
+		for k := 0; k < strings.Count(index, "["); k++ {
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			if t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+				t = t.Elem()
+			}
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func tagName(sf reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		if name := strings.SplitN(sf.Tag.Get(key), ",", 2)[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/middleware/validate"
+	mids "github.com/cr-mao/lori/transport/http/middlewares"
+)
+
+type bindItem struct {
+	ID int `json:"id" binding:"required"`
+}
+
+type bindUser struct {
+	Name  string      `json:"name" binding:"required,max=8"`
+	Items []*bindItem `json:"items" binding:"dive"`
+}
+
+func TestBindStruct(t *testing.T) {
+	s := NewServer(WithMode(gin.TestMode))
+	s.POST("/user", func(c *gin.Context) {
+		var u bindUser
+		if err := Bind(c, &u); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.String(http.StatusOK, u.Name)
+	})
+
+	tests := []struct {
+		name   string
+		body   string
+		code   int
+		fields []errors.FieldViolation
+	}{
+		{"ok", `{"name":"lori","items":[{"id":1}]}`, 0, nil},
+		{"decode", `{"name":`, ErrBind, nil},
+		{"validate", `{"name":"too long name","items":[{"id":1},{}]}`, validate.ErrValidation, []errors.FieldViolation{
+			{Field: "name", Description: "validation failed on max=8"},
+			{Field: "items[1].id", Description: "validation failed on required"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			s.ServeHTTP(w, req)
+			if tt.code == 0 {
+				if w.Code != http.StatusOK {
+					t.Fatalf("unexpected response %d %s", w.Code, w.Body)
+				}
+				return
+			}
+			var resp mids.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != http.StatusBadRequest || resp.Code != tt.code || len(resp.Fields) != len(tt.fields) {
+				t.Fatalf("unexpected response %d %s", w.Code, w.Body)
+			}
+			for i := range tt.fields {
+				if resp.Fields[i] != tt.fields[i] {
+					t.Errorf("expect field %v, got %v", tt.fields[i], resp.Fields[i])
+				}
+			}
+		})
+	}
+}
+
+type validateMsg struct{}
+
+func (validateMsg) Validate() error {
+	return errors.New("invalid")
+}
+
+func TestValidate(t *testing.T) {
+	for _, enable := range []bool{false, true} {
+		s := NewServer(WithMode(gin.TestMode), WithEnableValidate(enable))
+		s.GET("/", func(c *gin.Context) {
+			if err := Validate(c, validateMsg{}); err != nil {
+				_ = c.Error(err)
+				return
+			}
+			c.Status(http.StatusOK)
+		})
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if want := map[bool]int{false: http.StatusOK, true: http.StatusBadRequest}[enable]; w.Code != want {
+			t.Errorf("enable %v: expect %d, got %d", enable, want, w.Code)
+		}
+	}
+}
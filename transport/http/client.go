@@ -0,0 +1,443 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/metadata"
+	"github.com/cr-mao/lori/metric"
+	"github.com/cr-mao/lori/registry"
+	"github.com/cr-mao/lori/transport"
+	mids "github.com/cr-mao/lori/transport/http/middlewares"
+)
+
+// DecodeErrorFunc 把非 2xx 的返回解码为错误
+type DecodeErrorFunc func(ctx context.Context, resp *http.Response) error
+
+// ClientOption is HTTP client option.
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	endpoint        string
+	timeout         time.Duration
+	deadlineReserve time.Duration
+	discovery       registry.Discovery
+	discoveryWait   time.Duration
+	tlsConf         *tls.Config
+	transport       http.RoundTripper
+	enableTracing   bool
+	metric          metric.HttpClientMetric
+	retries         int
+	retryBackoff    time.Duration
+	retryStatuses   map[int]bool
+	errorDecoder    DecodeErrorFunc
+	userAgent       string
+}
+
+// WithClientEndpoint 调用目标, discovery:///user-service 或者 http://127.0.0.1:8000
+func WithClientEndpoint(endpoint string) ClientOption {
+	return func(o *clientOptions) {
+		o.endpoint = endpoint
+	}
+}
+
+// WithClientTimeout 默认超时, ctx 没有 deadline 时使用
+func WithClientTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithClientDeadlineReserve ctx 有 deadline 时, 留出 reserve 给本服务做后续处理,
+// 剩余时间通过 X-Request-Timeout 传给下游
+func WithClientDeadlineReserve(reserve time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.deadlineReserve = reserve
+	}
+}
+
+// WithClientDiscovery 服务发现, 配合 discovery:///name 使用
+func WithClientDiscovery(d registry.Discovery) ClientOption {
+	return func(o *clientOptions) {
+		o.discovery = d
+	}
+}
+
+// WithClientTLSConfig https, 服务发现时选择 https:// 的实例地址
+func WithClientTLSConfig(c *tls.Config) ClientOption {
+	return func(o *clientOptions) {
+		o.tlsConf = c
+	}
+}
+
+// WithClientTransport 自定义底层 RoundTripper, 默认 http.DefaultTransport
+func WithClientTransport(rt http.RoundTripper) ClientOption {
+	return func(o *clientOptions) {
+		o.transport = rt
+	}
+}
+
+// WithClientEnableTracing 链路追踪, 默认开启
+func WithClientEnableTracing(enable bool) ClientOption {
+	return func(o *clientOptions) {
+		o.enableTracing = enable
+	}
+}
+
+// WithClientMetric 请求耗时等指标
+func WithClientMetric(m metric.HttpClientMetric) ClientOption {
+	return func(o *clientOptions) {
+		o.metric = m
+	}
+}
+
+// WithClientRetry 幂等请求(GET, HEAD, PUT, DELETE, OPTIONS)在网络错误或者 502/503/504 时重试,
+// 每次重试换一个实例
+func WithClientRetry(retries int, backoff time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.retries = retries
+		o.retryBackoff = backoff
+	}
+}
+
+// WithClientRetryStatuses 需要重试的 http 状态码, 默认 502, 503, 504
+func WithClientRetryStatuses(statuses ...int) ClientOption {
+	return func(o *clientOptions) {
+		o.retryStatuses = make(map[int]bool, len(statuses))
+		for _, s := range statuses {
+			o.retryStatuses[s] = true
+		}
+	}
+}
+
+// WithClientErrorDecoder 自定义错误解码, 默认 DefaultErrorDecoder
+func WithClientErrorDecoder(d DecodeErrorFunc) ClientOption {
+	return func(o *clientOptions) {
+		o.errorDecoder = d
+	}
+}
+
+// WithClientUserAgent User-Agent 请求头
+func WithClientUserAgent(ua string) ClientOption {
+	return func(o *clientOptions) {
+		o.userAgent = ua
+	}
+}
+
+// CallOption 单次调用的选项
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	operation string
+	header    http.Header
+}
+
+// WithCallOperation 路由模板, 如 /v1/user/{id}, 用于指标和链路, 默认为请求路径
+func WithCallOperation(operation string) CallOption {
+	return func(o *callOptions) {
+		o.operation = operation
+	}
+}
+
+// WithCallHeader 附加请求头
+func WithCallHeader(key, value string) CallOption {
+	return func(o *callOptions) {
+		if o.header == nil {
+			o.header = make(http.Header)
+		}
+		o.header.Add(key, value)
+	}
+}
+
+// Client http 客户端, 支持服务发现, 轮询负载均衡, 超时, 重试, 链路, 指标, 错误码解码
+type Client struct {
+	opts     clientOptions
+	target   *target
+	resolver *resolver
+	cc       *http.Client
+}
+
+// NewClient 创建 http 客户端, 服务发现时等待拿到第一批实例
+func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
+	options := clientOptions{
+		timeout:       2000 * time.Millisecond,
+		discoveryWait: 10 * time.Second,
+		enableTracing: true,
+		transport:     http.DefaultTransport,
+		retryStatuses: map[int]bool{
+			http.StatusBadGateway:         true,
+			http.StatusServiceUnavailable: true,
+			http.StatusGatewayTimeout:     true,
+		},
+		errorDecoder: DefaultErrorDecoder,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.tlsConf != nil {
+		if tr, ok := options.transport.(*http.Transport); ok {
+			tr = tr.Clone()
+			tr.TLSClientConfig = options.tlsConf
+			options.transport = tr
+		}
+	}
+	insecure := options.tlsConf == nil
+	t, err := parseTarget(options.endpoint, insecure)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newResolver(ctx, options.discovery, t, insecure, options.discoveryWait)
+	if err != nil {
+		return nil, err
+	}
+	rt := options.transport
+	if options.metric != nil {
+		rt = options.metric.HttpClientMetricRoundTripper(rt)
+	}
+	return &Client{
+		opts:     options,
+		target:   t,
+		resolver: r,
+		cc:       &http.Client{Transport: rt},
+	}, nil
+}
+
+// Invoke 发起 json 请求, args 为 nil 时没有请求体; 非 2xx 通过错误解码器返回 lori 错误码
+//
+//	err := client.Invoke(ctx, http.MethodGet, "/v1/user/1", nil, &reply, WithCallOperation("/v1/user/{id}"))
+func (c *Client) Invoke(ctx context.Context, method, path string, args interface{}, reply interface{}, opts ...CallOption) error {
+	var body io.Reader
+	if args != nil {
+		data, err := json.Marshal(args)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if args != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(req, opts...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return c.opts.errorDecoder(req.Context(), resp)
+	}
+	if reply == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(reply)
+}
+
+// Do 发送请求, req.URL 只需要 path 和 query, scheme 和 host 由客户端选择; 不做错误解码
+func (c *Client) Do(req *http.Request, opts ...CallOption) (*http.Response, error) {
+	return c.do(req, opts...)
+}
+
+func (c *Client) do(req *http.Request, opts ...CallOption) (*http.Response, error) {
+	co := callOptions{operation: req.URL.Path}
+	for _, o := range opts {
+		o(&co)
+	}
+	for k, v := range co.header {
+		req.Header[k] = v
+	}
+	if c.opts.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", c.opts.userAgent)
+	}
+
+	ctx, cancel, err := c.budget(req.Context(), req.Header)
+	if err != nil {
+		return nil, err
+	}
+	// metadata 透传
+	metadata.Inject(ctx, NewHeaderCarrier(req.Header))
+	tr := &Transport{
+		endpoint:     c.opts.endpoint,
+		operation:    co.operation,
+		reqHeader:    headerCarrier(req.Header),
+		replyHeader:  headerCarrier(http.Header{}),
+		request:      req,
+		pathTemplate: co.operation,
+	}
+	ctx = transport.NewClientContext(ctx, tr)
+
+	var span trace.Span
+	if c.opts.enableTracing {
+		ctx, span = otel.Tracer("lori/transport/http").Start(ctx, co.operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("http.method", req.Method),
+				attribute.String("http.target", req.URL.RequestURI()),
+				attribute.String("peer.service", c.target.name),
+			))
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	}
+
+	resp, err := c.roundTrip(ctx, req)
+	if span != nil {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+			if resp.StatusCode >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+			}
+		}
+		span.End()
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// 响应头写入 ReplyHeader, 和 server 端一样可以通过 Transport 读取
+	for k, v := range resp.Header {
+		tr.replyHeader[k] = append(tr.replyHeader[k], v...)
+	}
+	// 返回体读完之前不能取消 ctx, 关闭时取消
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// budget 没有 deadline 时使用默认超时, 有 deadline 时减去 reserve, 剩余时间写入 X-Request-Timeout
+func (c *Client) budget(ctx context.Context, header http.Header) (context.Context, context.CancelFunc, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		if c.opts.timeout <= 0 {
+			return ctx, func() {}, nil
+		}
+		ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+		return ctx, cancel, InjectDeadline(ctx, header, 0)
+	}
+	if err := InjectDeadline(ctx, header, c.opts.deadlineReserve); err != nil {
+		return nil, nil, err
+	}
+	if c.opts.deadlineReserve <= 0 {
+		return ctx, func() {}, nil
+	}
+	deadline, _ := ctx.Deadline()
+	ctx, cancel := context.WithDeadline(ctx, deadline.Add(-c.opts.deadlineReserve))
+	return ctx, cancel, nil
+}
+
+// roundTrip 选择实例发送请求, 幂等请求按配置重试
+func (c *Client) roundTrip(ctx context.Context, req *http.Request) (*http.Response, error) {
+	retries := 0
+	if isIdempotent(req.Method) && (req.Body == nil || req.GetBody != nil) {
+		retries = c.opts.retries
+	}
+	for attempt := 0; ; attempt++ {
+		host, err := c.resolver.pick()
+		if err != nil {
+			return nil, err
+		}
+		r := req.Clone(ctx)
+		r.URL.Scheme = "http"
+		if c.opts.tlsConf != nil {
+			r.URL.Scheme = "https"
+		}
+		r.URL.Host = host
+		r.Host = host
+		if attempt > 0 && req.GetBody != nil {
+			if r.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.cc.Do(r)
+		if attempt >= retries || ctx.Err() != nil {
+			return resp, err
+		}
+		if err == nil && !c.opts.retryStatuses[resp.StatusCode] {
+			return resp, nil
+		}
+		if err == nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(c.opts.retryBackoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Close 停止服务发现的监听
+func (c *Client) Close() error {
+	return c.resolver.Close()
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// cancelBody 返回体关闭时释放超时 context
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// ResponseError 非 lori 错误格式的非 2xx 返回
+type ResponseError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("http status %d: %s", e.StatusCode, bytes.TrimSpace(e.Body))
+}
+
+// DefaultErrorDecoder 解析 lori 统一错误格式 {"code":..., "message":...}, 还原为 lori 错误码,
+// 本地没有注册的错误码使用返回中的 http 状态码和信息; 其他格式返回 *ResponseError
+func DefaultErrorDecoder(_ context.Context, resp *http.Response) error {
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	var e mids.ErrorResponse
+	if err = json.Unmarshal(data, &e); err != nil || e.Code == 0 {
+		return &ResponseError{StatusCode: resp.StatusCode, Body: data}
+	}
+	err = errors.WithCoder(errors.NewCoder(e.Code, resp.StatusCode, e.Message, e.Reference),
+		"http %d: %s", resp.StatusCode, e.Message)
+	if e.RequestID != "" {
+		err = errors.WithFields(err, "request_id", e.RequestID)
+	}
+	if len(e.Fields) > 0 {
+		err = errors.WithViolations(err, e.Fields...)
+	}
+	return err
+}
@@ -0,0 +1,202 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/metadata"
+	"github.com/cr-mao/lori/registry"
+	"github.com/cr-mao/lori/transport"
+	mids "github.com/cr-mao/lori/transport/http/middlewares"
+)
+
+const errUserNotFound = 140404
+
+type userCoder struct{}
+
+func (userCoder) Code() int         { return errUserNotFound }
+func (userCoder) HTTPStatus() int   { return http.StatusNotFound }
+func (userCoder) String() string    { return "user not found" }
+func (userCoder) Reference() string { return "" }
+
+type staticDiscovery struct {
+	instances []*registry.ServiceInstance
+}
+
+func (d *staticDiscovery) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
+	return d.instances, nil
+}
+
+func (d *staticDiscovery) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	return &staticWatcher{ctx: ctx, d: d}, nil
+}
+
+type staticWatcher struct {
+	ctx  context.Context
+	d    *staticDiscovery
+	sent bool
+}
+
+func (w *staticWatcher) Next() ([]*registry.ServiceInstance, error) {
+	if !w.sent {
+		w.sent = true
+		return w.d.instances, nil
+	}
+	<-w.ctx.Done()
+	return nil, w.ctx.Err()
+}
+
+func (w *staticWatcher) Stop() error { return nil }
+
+func newUserServer(t *testing.T) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(mids.ErrorHandler())
+	r.GET("/v1/user/:id", func(c *gin.Context) {
+		if c.Param("id") != "1" {
+			_ = c.Error(errors.WithCode(errUserNotFound, "user %s not in db", c.Param("id")))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"name":    "lori",
+			"tenant":  c.GetHeader("x-md-global-tenant"),
+			"timeout": c.GetHeader(mids.RequestTimeoutHeader),
+		})
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClientInvoke(t *testing.T) {
+	errors.Register(userCoder{})
+	srv := newUserServer(t)
+	client, err := NewClient(context.Background(), WithClientEndpoint(srv.URL), WithClientTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := metadata.AppendToClientContext(context.Background(), "x-md-global-tenant", "1001")
+	var reply struct {
+		Name    string `json:"name"`
+		Tenant  string `json:"tenant"`
+		Timeout string `json:"timeout"`
+	}
+	if err = client.Invoke(ctx, http.MethodGet, "/v1/user/1", nil, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Name != "lori" || reply.Tenant != "1001" || reply.Timeout == "" {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+
+	err = client.Invoke(context.Background(), http.MethodGet, "/v1/user/2", nil, &reply)
+	if !errors.IsCode(err, errUserNotFound) {
+		t.Fatalf("expect code %d, got %v", errUserNotFound, err)
+	}
+	if errors.ParseCoder(err).HTTPStatus() != http.StatusNotFound {
+		t.Errorf("unexpected coder %+v", errors.ParseCoder(err))
+	}
+}
+
+func TestClientDiscoveryRetry(t *testing.T) {
+	var bad, good int32
+	badSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&bad, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer badSrv.Close()
+	goodSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&good, 1)
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": "ok"})
+	}))
+	defer goodSrv.Close()
+
+	d := &staticDiscovery{instances: []*registry.ServiceInstance{
+		{Name: "greeter", Endpoints: []string{badSrv.URL, "grpc://127.0.0.1:9000"}},
+		{Name: "greeter", Endpoints: []string{goodSrv.URL}},
+	}}
+	client, err := NewClient(context.Background(),
+		WithClientEndpoint("discovery:///greeter"),
+		WithClientDiscovery(d),
+		WithClientRetry(1, 0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		var reply map[string]string
+		if err = client.Invoke(context.Background(), http.MethodGet, "/hello", nil, &reply); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 轮询到 503 的实例后换一个实例重试
+	if bad != 2 || good != 2 {
+		t.Errorf("expect 2 bad and 2 good requests, got %d and %d", bad, good)
+	}
+
+	// 非幂等请求不重试
+	err = client.Invoke(context.Background(), http.MethodPost, "/hello", map[string]string{}, nil)
+	var rerr *ResponseError
+	if !errors.As(err, &rerr) || rerr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expect 503 response error, got %v", err)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := map[string]string{
+		"discovery:///greeter":  "discovery||greeter",
+		"http://127.0.0.1:8000": "http|127.0.0.1:8000|",
+		"127.0.0.1:8000":        "http|127.0.0.1:8000|",
+	}
+	for in, want := range tests {
+		tg, err := parseTarget(in, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join([]string{tg.scheme, tg.authority, tg.name}, "|"); got != want {
+			t.Errorf("parseTarget(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestClientTransportReplyHeader(t *testing.T) {
+	srv := newUserServer(t)
+	var got string
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		tr, ok := transport.FromClientContext(r.Context())
+		if !ok {
+			t.Fatal("expect client transport in context")
+		}
+		// client 端的 ReplyHeader 可以写入, 不会因为 nil map panic
+		tr.ReplyHeader().Set("X-Lori", "1")
+		got = tr.ReplyHeader().Get("X-Lori")
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	client, err := NewClient(context.Background(), WithClientEndpoint(srv.URL), WithClientTransport(rt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err = client.Invoke(context.Background(), http.MethodGet, "/v1/user/1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got != "1" {
+		t.Errorf("expect reply header set, got %q", got)
+	}
+}
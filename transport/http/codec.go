@@ -0,0 +1,245 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/cr-mao/lori/errors"
+)
+
+// protoc-gen-lori-http 生成的路由使用的编解码, 请求参数绑定到 proto 消息, 返回 proto 消息
+
+// ErrBind 请求参数绑定失败
+const ErrBind = 100003
+
+func init() {
+	errors.Register(errors.NewReasonCoder(ErrBind, http.StatusBadRequest, "BIND", "Invalid request parameters", ""))
+}
+
+var (
+	// 请求体中未知的字段忽略, 方便服务端先删字段
+	unmarshalOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+	// 字段名和 proto 定义保持一致(snake_case), 零值也返回
+	marshalOptions = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+)
+
+// errUnknownField 消息中没有这个字段
+var errUnknownField = fmt.Errorf("unknown field")
+
+// BindVars 路由参数绑定到 msg, 参数名为字段路径, 比如 /v1/user/:id, /v1/user/:profile.name
+func BindVars(c *gin.Context, msg proto.Message) error {
+	for _, p := range c.Params {
+		// 通配参数 *name 的值以 / 开头
+		if err := setField(msg.ProtoReflect(), p.Key, strings.TrimPrefix(p.Value, "/")); err != nil {
+			return errors.WrapC(err, ErrBind, "bind path parameter %s", p.Key)
+		}
+	}
+	return nil
+}
+
+// BindQuery query 参数绑定到 msg, key 为字段路径, 重复的 key 绑定到 repeated 字段, 未知的 key 忽略
+func BindQuery(c *gin.Context, msg proto.Message) error {
+	for key, values := range c.Request.URL.Query() {
+		err := setField(msg.ProtoReflect(), key, values...)
+		if err != nil && !errors.Is(err, errUnknownField) {
+			return errors.WrapC(err, ErrBind, "bind query parameter %s", key)
+		}
+	}
+	return nil
+}
+
+// BindBody 请求体绑定到 msg, body 为 "*" 时绑定整个消息, 为字段名时只绑定该字段, 为空时不绑定;
+// Content-Type 为 protobuf 时按 protobuf 解码, 否则按 json 解码
+func BindBody(c *gin.Context, msg proto.Message, body string) error {
+	if body == "" {
+		return nil
+	}
+	data, err := c.GetRawData()
+	if err != nil {
+		return errors.WrapC(err, ErrBind, "read request body")
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if body != "*" {
+		err = bindBodyField(c, msg, body, data)
+	} else if isProtobuf(c.ContentType()) {
+		err = proto.UnmarshalOptions{Merge: true}.Unmarshal(data, msg)
+	} else {
+		err = unmarshalOptions.Unmarshal(data, msg)
+	}
+	if err != nil {
+		return errors.WrapC(err, ErrBind, "bind request body")
+	}
+	return nil
+}
+
+func bindBodyField(c *gin.Context, msg proto.Message, body string, data []byte) error {
+	m := msg.ProtoReflect()
+	fd := fieldByName(m, body)
+	if fd == nil {
+		return fmt.Errorf("%w: %s", errUnknownField, body)
+	}
+	if isProtobuf(c.ContentType()) {
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return fmt.Errorf("protobuf body field %s must be a message", body)
+		}
+		return proto.UnmarshalOptions{Merge: true}.Unmarshal(data, m.Mutable(fd).Message().Interface())
+	}
+	// {"body": data} 解码到一个新消息, 再合并, 标量和 repeated 字段也能作为 body
+	wrapped, err := json.Marshal(map[string]json.RawMessage{fd.JSONName(): data})
+	if err != nil {
+		return err
+	}
+	tmp := m.New().Interface()
+	if err = unmarshalOptions.Unmarshal(wrapped, tmp); err != nil {
+		return err
+	}
+	proto.Merge(msg, tmp)
+	return nil
+}
+
+// Result 返回 200, Accept 为 protobuf 时返回 protobuf, 否则返回 json;
+// responseBody 不为空时只返回该字段, 两种编码一致, 只支持非 repeated 的消息字段
+func Result(c *gin.Context, msg proto.Message, responseBody string) {
+	if responseBody != "" {
+		fd := fieldByName(msg.ProtoReflect(), responseBody)
+		if fd == nil || fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			_ = c.Error(fmt.Errorf("response_body %s is not a singular message field of %s",
+				responseBody, msg.ProtoReflect().Descriptor().FullName()))
+			return
+		}
+		msg = msg.ProtoReflect().Get(fd).Message().Interface()
+	}
+	if c.NegotiateFormat(binding.MIMEJSON, binding.MIMEPROTOBUF) == binding.MIMEPROTOBUF {
+		c.ProtoBuf(http.StatusOK, msg)
+		return
+	}
+	data, err := marshalOptions.Marshal(msg)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Data(http.StatusOK, binding.MIMEJSON+"; charset=utf-8", data)
+}
+
+func isProtobuf(contentType string) bool {
+	return contentType == binding.MIMEPROTOBUF || contentType == "application/protobuf"
+}
+
+// fieldByName 按 proto 字段名或者 json 名查找
+func fieldByName(m protoreflect.Message, name string) protoreflect.FieldDescriptor {
+	fields := m.Descriptor().Fields()
+	if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	return fields.ByJSONName(name)
+}
+
+// setField 按字段路径(a.b.c)设置字段值, repeated 字段追加所有值, 其他字段取最后一个值
+func setField(m protoreflect.Message, path string, values ...string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	parts := strings.Split(path, ".")
+	for _, name := range parts[:len(parts)-1] {
+		fd := fieldByName(m, name)
+		if fd == nil {
+			return fmt.Errorf("%w: %s", errUnknownField, path)
+		}
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return fmt.Errorf("field %s is not a message", name)
+		}
+		m = m.Mutable(fd).Message()
+	}
+	fd := fieldByName(m, parts[len(parts)-1])
+	if fd == nil {
+		return fmt.Errorf("%w: %s", errUnknownField, path)
+	}
+	switch {
+	case fd.IsMap():
+		return fmt.Errorf("map field %s is not supported", path)
+	case fd.IsList():
+		list := m.Mutable(fd).List()
+		for _, v := range values {
+			pv, err := parseValue(fd, v, list.NewElement)
+			if err != nil {
+				return err
+			}
+			list.Append(pv)
+		}
+		return nil
+	default:
+		pv, err := parseValue(fd, values[len(values)-1], func() protoreflect.Value { return m.NewField(fd) })
+		if err != nil {
+			return err
+		}
+		m.Set(fd, pv)
+		return nil
+	}
+}
+
+// parseValue 字符串转换为字段类型的值, 消息类型只支持 Timestamp, Duration, wrappers 等 json 为标量的类型
+func parseValue(fd protoreflect.FieldDescriptor, v string, newValue func() protoreflect.Value) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(v)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		i, err := strconv.ParseInt(v, 10, 32)
+		return protoreflect.ValueOfInt32(int32(i)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		i, err := strconv.ParseInt(v, 10, 64)
+		return protoreflect.ValueOfInt64(i), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		i, err := strconv.ParseUint(v, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(i)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		i, err := strconv.ParseUint(v, 10, 64)
+		return protoreflect.ValueOfUint64(i), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(v, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(v, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(v), nil
+	case protoreflect.BytesKind:
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			b, err = base64.URLEncoding.DecodeString(v)
+		}
+		return protoreflect.ValueOfBytes(b), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(v)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		i, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid enum value %q for %s", v, fd.FullName())
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(i)), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		pv := newValue()
+		msg := pv.Message().Interface()
+		// 先按 json 原值解析(数字, bool), 再按 json 字符串解析(Timestamp, Duration 等)
+		if err := protojson.Unmarshal([]byte(v), msg); err != nil {
+			if err = protojson.Unmarshal([]byte(strconv.Quote(v)), msg); err != nil {
+				return protoreflect.Value{}, err
+			}
+		}
+		return pv, nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported field kind %s", fd.Kind())
+}
@@ -0,0 +1,118 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/cr-mao/lori/errors"
+	mids "github.com/cr-mao/lori/transport/http/middlewares"
+)
+
+func TestBind(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var got *descriptorpb.FieldDescriptorProto
+	r := gin.New()
+	r.POST("/v1/fields/:name", func(c *gin.Context) {
+		in := &descriptorpb.FieldDescriptorProto{}
+		for _, bind := range []func() error{
+			func() error { return BindBody(c, in, "options") },
+			func() error { return BindQuery(c, in) },
+			func() error { return BindVars(c, in) },
+		} {
+			if err := bind(); err != nil {
+				c.String(errors.ParseCoder(err).HTTPStatus(), err.Error())
+				return
+			}
+		}
+		got = in
+		Result(c, in, "")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/fields/id?number=3&label=LABEL_REPEATED&jsonName=userId&utm_source=x",
+		strings.NewReader(`{"packed": true, "unknown": 1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	want := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("id"),
+		Number:   proto.Int32(3),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+		JsonName: proto.String("userId"),
+		Options:  &descriptorpb.FieldOptions{Packed: proto.Bool(true)},
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("expect %v, got %v", want, got)
+	}
+	var reply map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &reply); err != nil || reply["json_name"] != "userId" {
+		t.Errorf("expect proto field names in response, got %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/fields/id?number=abc", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expect 400 for invalid query, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSetField(t *testing.T) {
+	m := &descriptorpb.SourceCodeInfo_Location{}
+	if err := setField(m.ProtoReflect(), "path", "1", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Path) != 2 || m.Path[1] != 2 {
+		t.Errorf("expect repeated values appended, got %v", m.Path)
+	}
+	v := &wrapperspb.Int64Value{}
+	if err := setField(v.ProtoReflect(), "value", "7"); err != nil || v.Value != 7 {
+		t.Errorf("unexpected value %v %v", v, err)
+	}
+}
+
+func TestResultResponseBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(mids.ErrorHandler())
+	msg := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("a.proto"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/a")},
+	}
+	r.GET("/:field", func(c *gin.Context) {
+		Result(c, msg, c.Param("field"))
+	})
+	serve := func(field, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+field, nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve("options", binding.MIMEJSON); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"go_package":"example.com/a"`) {
+		t.Errorf("unexpected json response %d %s", w.Code, w.Body.String())
+	}
+	w := serve("options", binding.MIMEPROTOBUF)
+	var got descriptorpb.FileOptions
+	if w.Code != http.StatusOK || proto.Unmarshal(w.Body.Bytes(), &got) != nil || got.GetGoPackage() != "example.com/a" {
+		t.Errorf("unexpected protobuf response %d %v", w.Code, &got)
+	}
+	// 不是消息字段时两种编码都返回错误
+	for _, accept := range []string{binding.MIMEJSON, binding.MIMEPROTOBUF} {
+		if w := serve("name", accept); w.Code != http.StatusInternalServerError {
+			t.Errorf("%s: expect 500 for scalar response_body, got %d %s", accept, w.Code, w.Body.String())
+		}
+	}
+}
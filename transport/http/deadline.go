@@ -0,0 +1,24 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	mids "github.com/cr-mao/lori/transport/http/middlewares"
+)
+
+// InjectDeadline 发起 http 请求前调用, 把 ctx 剩余时间减去 reserve 写入 X-Request-Timeout,
+// 下游据此截断自己的超时; 剩余时间不够时返回 context.DeadlineExceeded, 不要再发出请求
+func InjectDeadline(ctx context.Context, header http.Header, reserve time.Duration) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	remaining := time.Until(deadline) - reserve
+	if remaining <= 0 {
+		return context.DeadlineExceeded
+	}
+	header.Set(mids.RequestTimeoutHeader, mids.FormatRequestTimeout(remaining))
+	return nil
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	mids "github.com/cr-mao/lori/transport/http/middlewares"
+)
+
+func TestInjectDeadline(t *testing.T) {
+	header := http.Header{}
+	if err := InjectDeadline(context.Background(), header, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get(mids.RequestTimeoutHeader) != "" {
+		t.Errorf("expect no header without deadline")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := InjectDeadline(ctx, header, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	d, ok := mids.ParseRequestTimeout(header.Get(mids.RequestTimeoutHeader))
+	if !ok || d > 2*time.Second || d < time.Second {
+		t.Errorf("expect about 2s, got %v", d)
+	}
+
+	if err := InjectDeadline(ctx, header, 5*time.Second); err != context.DeadlineExceeded {
+		t.Errorf("expect %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestTimeoutMiddleware_RequestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(mids.TimeoutMiddleware(time.Minute))
+	var remaining time.Duration
+	var called bool
+	r.GET("/ping", func(c *gin.Context) {
+		called = true
+		deadline, _ := c.Request.Context().Deadline()
+		remaining = time.Until(deadline)
+		c.String(http.StatusOK, "pong")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(mids.RequestTimeoutHeader, "500")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if remaining > 500*time.Millisecond {
+		t.Errorf("expect deadline capped by header, got %v", remaining)
+	}
+
+	called = false
+	req = httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(mids.RequestTimeoutHeader, "0")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if called || w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expect expired request rejected with 504, got %d called=%v", w.Code, called)
+	}
+}
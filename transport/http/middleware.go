@@ -0,0 +1,40 @@
+package http
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cr-mao/lori/middleware"
+)
+
+// middlewareHandler 把与传输层无关的中间件链适配成 gin 中间件, req 为 *http.Request;
+// 后续 handler 通过 c.Error 记录的最后一个错误作为链的返回值,
+// 链返回的错误(比如鉴权失败, panic)记录到 c.Errors, 由 ErrorHandler 统一返回
+func (s *Server) middlewareHandler() gin.HandlerFunc {
+	chain := middleware.Chain(s.ms...)
+	return func(c *gin.Context) {
+		var called bool
+		next := func(ctx context.Context, req interface{}) (interface{}, error) {
+			called = true
+			c.Request = c.Request.WithContext(ctx)
+			c.Next()
+			if e := c.Errors.Last(); e != nil {
+				return nil, e.Err
+			}
+			return nil, nil
+		}
+		_, err := chain(next)(c.Request.Context(), c.Request)
+		if err != nil {
+			if e := c.Errors.Last(); e == nil || e.Err != err {
+				_ = c.Error(err)
+			}
+			c.Abort()
+			return
+		}
+		// 中间件没有调用 next, 不再执行后面的 handler
+		if !called {
+			c.Abort()
+		}
+	}
+}
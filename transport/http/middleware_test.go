@@ -0,0 +1,83 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/middleware"
+	"github.com/cr-mao/lori/middleware/recovery"
+	"github.com/cr-mao/lori/transport"
+	mids "github.com/cr-mao/lori/transport/http/middlewares"
+)
+
+func TestServerMiddleware(t *testing.T) {
+	var (
+		operation string
+		handled   error
+	)
+	observe := func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				operation = tr.Operation()
+			}
+			if _, ok := req.(*http.Request); !ok {
+				t.Errorf("expect *http.Request, got %T", req)
+			}
+			reply, err := next(ctx, req)
+			handled = err
+			return reply, err
+		}
+	}
+	deny := func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if req.(*http.Request).Header.Get("Authorization") == "" {
+				return nil, errors.WithCode(errUserNotFound, "no token")
+			}
+			return next(ctx, req)
+		}
+	}
+	errors.Register(userCoder{})
+	s := NewServer(WithMode(gin.TestMode), WithTimeout(0), WithMiddleware(recovery.Recovery(), observe, deny))
+	s.GET("/v1/user/:id", func(c *gin.Context) {
+		switch c.Param("id") {
+		case "panic":
+			panic("boom")
+		case "err":
+			_ = c.Error(errors.WithCode(errUserNotFound, "user not found"))
+		default:
+			c.Status(http.StatusNoContent)
+		}
+	})
+
+	serve := func(path string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth {
+			req.Header.Set("Authorization", "Bearer lori")
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve("/v1/user/1", true); w.Code != http.StatusNoContent || operation != "/v1/user/:id" || handled != nil {
+		t.Errorf("unexpected response %d, operation %s, err %v", w.Code, operation, handled)
+	}
+	w := serve("/v1/user/1", false)
+	var resp mids.ErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusNotFound || resp.Code != errUserNotFound {
+		t.Errorf("expect rejected by middleware, got %d %s", w.Code, w.Body.String())
+	}
+	if w = serve("/v1/user/err", true); w.Code != http.StatusNotFound || !errors.IsCode(handled, errUserNotFound) {
+		t.Errorf("expect handler error seen by middleware, got %d %v", w.Code, handled)
+	}
+	if w = serve("/v1/user/panic", true); w.Code != http.StatusInternalServerError {
+		t.Errorf("expect 500 after panic, got %d %s", w.Code, w.Body.String())
+	}
+}
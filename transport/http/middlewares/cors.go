@@ -1,26 +1,219 @@
 package middlewares
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Cors() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		method := c.Request.Method
+type corsOptions struct {
+	allowOrigins        []string // 精确匹配, 小写
+	originPatterns      [][2]string
+	allowAllOrigins     bool
+	originsSet          bool // 没有设置来源时默认允许所有来源
+	allowOriginFunc     func(origin string) bool
+	allowMethods        []string
+	allowHeaders        []string
+	exposeHeaders       []string
+	allowCredentials    bool
+	maxAge              time.Duration
+	allowPrivateNetwork bool
+}
+
+// CorsOption cors 选项
+type CorsOption func(*corsOptions)
+
+// WithAllowOrigins 允许的来源, 支持一个通配符, 比如 https://*.example.com; "*" 表示允许所有来源, 默认 "*"
+func WithAllowOrigins(origins ...string) CorsOption {
+	return func(o *corsOptions) {
+		o.allowOrigins, o.originPatterns, o.allowAllOrigins = nil, nil, false
+		o.originsSet = true
+		for _, origin := range origins {
+			origin = strings.ToLower(strings.TrimSpace(origin))
+			switch {
+			case origin == "*":
+				o.allowAllOrigins = true
+			case strings.Contains(origin, "*"):
+				i := strings.IndexByte(origin, '*')
+				o.originPatterns = append(o.originPatterns, [2]string{origin[:i], origin[i+1:]})
+			default:
+				o.allowOrigins = append(o.allowOrigins, origin)
+			}
+		}
+	}
+}
+
+// WithAllowOriginFunc 自定义来源校验, 在 WithAllowOrigins 不匹配时调用;
+// 只设置了校验函数时不再默认允许所有来源
+func WithAllowOriginFunc(f func(origin string) bool) CorsOption {
+	return func(o *corsOptions) {
+		o.allowOriginFunc = f
+	}
+}
+
+// WithAllowMethods 允许的方法, 默认 GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS
+func WithAllowMethods(methods ...string) CorsOption {
+	return func(o *corsOptions) {
+		o.allowMethods = methods
+	}
+}
+
+// WithAllowHeaders 允许的请求头, "*" 表示允许预检请求中的所有请求头
+func WithAllowHeaders(headers ...string) CorsOption {
+	return func(o *corsOptions) {
+		o.allowHeaders = headers
+	}
+}
+
+// WithExposeHeaders 浏览器可以读取的返回头
+func WithExposeHeaders(headers ...string) CorsOption {
+	return func(o *corsOptions) {
+		o.exposeHeaders = headers
+	}
+}
+
+// WithAllowCredentials 允许携带 cookie, 此时 Access-Control-Allow-Origin 返回请求的来源而不是 "*";
+// 必须同时用 WithAllowOrigins 或 WithAllowOriginFunc 指定来源, 不能允许所有来源
+func WithAllowCredentials(allow bool) CorsOption {
+	return func(o *corsOptions) {
+		o.allowCredentials = allow
+	}
+}
+
+// WithMaxAge 预检结果的缓存时间
+func WithMaxAge(maxAge time.Duration) CorsOption {
+	return func(o *corsOptions) {
+		o.maxAge = maxAge
+	}
+}
+
+// WithAllowPrivateNetwork 允许公网页面访问内网服务(Private Network Access 预检)
+func WithAllowPrivateNetwork(allow bool) CorsOption {
+	return func(o *corsOptions) {
+		o.allowPrivateNetwork = allow
+	}
+}
+
+// Cors 跨域中间件, 默认允许所有来源, 不允许携带 cookie;
+// 具名中间件 "cors" 的参数见 corsFactory
+//
+// 允许携带 cookie 时来源为 "*" 会 panic, 否则任何网站都能带着用户的 cookie 调用接口
+//
+// 不允许的来源: 普通请求不返回跨域头, 由浏览器拦截; 预检请求返回 403
+func Cors(opts ...CorsOption) gin.HandlerFunc {
+	o := newCorsOptions(opts...)
+	if err := o.validate(); err != nil {
+		panic(err)
+	}
+	allowMethods := strings.Join(o.allowMethods, ", ")
+	allowHeaders := strings.Join(o.allowHeaders, ", ")
+	reflectHeaders := len(o.allowHeaders) == 1 && o.allowHeaders[0] == "*"
+	exposeHeaders := strings.Join(o.exposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(o.maxAge / time.Second))
 
-		c.Header("Access-Control-Allow-Origin", "*")
-		//指定 可以请求的头， OPTIONS直接返回204
-		//c.Header("Access-Control-Allow-Headers", "Content-Type,AccessToken,X-CSRF-Token, Authorization, Token, x-token")
-		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, DELETE, PATCH, PUT")
-		c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Content-Type")
-		c.Header("Access-Control-Allow-Credentials", "true")
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		h := c.Writer.Header()
+		// 返回内容和来源有关时必须带 Vary, 否则缓存会把一个来源的结果返回给另一个来源
+		if preflight {
+			h.Add("Vary", "Origin")
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		} else if !o.allowAllOrigins {
+			h.Add("Vary", "Origin")
+		}
+		if origin == "" {
+			c.Next()
+			return
+		}
+		if !o.allowed(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
 
-		if method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
+		if o.allowAllOrigins {
+			h.Set("Access-Control-Allow-Origin", "*")
 		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if o.allowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			if exposeHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
 			c.Next()
+			return
+		}
+
+		h.Set("Access-Control-Allow-Methods", allowMethods)
+		if reflectHeaders {
+			if req := c.GetHeader("Access-Control-Request-Headers"); req != "" {
+				h.Set("Access-Control-Allow-Headers", req)
+			}
+		} else if allowHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", allowHeaders)
+		}
+		if o.maxAge > 0 {
+			h.Set("Access-Control-Max-Age", maxAge)
+		}
+		if o.allowPrivateNetwork && c.GetHeader("Access-Control-Request-Private-Network") == "true" {
+			h.Set("Access-Control-Allow-Private-Network", "true")
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+func newCorsOptions(opts ...CorsOption) *corsOptions {
+	o := &corsOptions{
+		allowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+			http.MethodDelete, http.MethodHead, http.MethodOptions},
+		allowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization",
+			RequestIDHeader, RequestTimeoutHeader},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if !o.originsSet && o.allowOriginFunc == nil {
+		o.allowAllOrigins = true
+	}
+	return o
+}
+
+// errCredentialsWildcard 允许携带 cookie 时不能允许所有来源
+var errCredentialsWildcard = errors.New("cors: allow credentials requires an explicit origin allow-list, not \"*\"")
+
+func (o *corsOptions) validate() error {
+	if o.allowCredentials && o.allowAllOrigins {
+		return errCredentialsWildcard
+	}
+	return nil
+}
+
+func (o *corsOptions) allowed(origin string) bool {
+	if o.allowAllOrigins {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	for _, allow := range o.allowOrigins {
+		if lower == allow {
+			return true
+		}
+	}
+	for _, p := range o.originPatterns {
+		if len(lower) >= len(p[0])+len(p[1]) && strings.HasPrefix(lower, p[0]) && strings.HasSuffix(lower, p[1]) {
+			return true
 		}
 	}
+	return o.allowOriginFunc != nil && o.allowOriginFunc(origin)
 }
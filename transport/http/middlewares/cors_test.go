@@ -0,0 +1,130 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newCorsEngine(opts ...CorsOption) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Cors(opts...))
+	r.GET("/user", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return r
+}
+
+func corsRequest(r *gin.Engine, method, origin string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/user", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCorsDefault(t *testing.T) {
+	r := newCorsEngine()
+	w := corsRequest(r, http.MethodGet, "https://a.com", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("unexpected default cors headers %v", w.Header())
+	}
+}
+
+func TestCorsCredentials(t *testing.T) {
+	r := newCorsEngine(
+		WithAllowOrigins("https://app.example.com", "https://*.preview.example.com"),
+		WithAllowCredentials(true),
+		WithExposeHeaders(RequestIDHeader),
+		WithMaxAge(10*time.Minute),
+		WithAllowPrivateNetwork(true),
+	)
+
+	w := corsRequest(r, http.MethodGet, "https://app.example.com", nil)
+	h := w.Header()
+	if w.Code != http.StatusOK || h.Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		h.Get("Access-Control-Allow-Credentials") != "true" || h.Get("Vary") != "Origin" ||
+		h.Get("Access-Control-Expose-Headers") != RequestIDHeader {
+		t.Errorf("unexpected cors headers %d %v", w.Code, h)
+	}
+
+	w = corsRequest(r, http.MethodOptions, "https://pr-1.preview.example.com", map[string]string{
+		"Access-Control-Request-Method":          http.MethodPost,
+		"Access-Control-Request-Private-Network": "true",
+	})
+	h = w.Header()
+	if w.Code != http.StatusNoContent || h.Get("Access-Control-Allow-Origin") != "https://pr-1.preview.example.com" ||
+		h.Get("Access-Control-Max-Age") != "600" || h.Get("Access-Control-Allow-Private-Network") != "true" ||
+		h.Get("Access-Control-Allow-Methods") == "" || len(h.Values("Vary")) != 3 {
+		t.Errorf("unexpected preflight response %d %v", w.Code, h)
+	}
+
+	// 不允许的来源
+	w = corsRequest(r, http.MethodGet, "https://evil.com", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expect no cors headers for disallowed origin, got %v", w.Header())
+	}
+	w = corsRequest(r, http.MethodOptions, "https://evil.com", map[string]string{"Access-Control-Request-Method": http.MethodGet})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expect disallowed preflight rejected, got %d", w.Code)
+	}
+}
+
+func TestCorsReflectHeaders(t *testing.T) {
+	r := newCorsEngine(WithAllowHeaders("*"))
+	w := corsRequest(r, http.MethodOptions, "https://a.com", map[string]string{
+		"Access-Control-Request-Method":  http.MethodPut,
+		"Access-Control-Request-Headers": "x-token, content-type",
+	})
+	if w.Header().Get("Access-Control-Allow-Headers") != "x-token, content-type" {
+		t.Errorf("expect request headers reflected, got %v", w.Header())
+	}
+}
+
+func TestCorsCredentialsWildcard(t *testing.T) {
+	for _, opts := range [][]CorsOption{
+		{WithAllowCredentials(true)},
+		{WithAllowOrigins("https://a.com", "*"), WithAllowCredentials(true)},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expect panic when credentials are allowed for all origins")
+				}
+			}()
+			Cors(opts...)
+		}()
+	}
+
+	for _, options := range []map[string]interface{}{
+		{"allow_credentials": true},
+		{"allow_credentials": true, "allow_origins": []interface{}{"*"}},
+	} {
+		if _, err := corsFactory(options); err == nil {
+			t.Errorf("expect error for %v", options)
+		}
+	}
+	if _, err := corsFactory(map[string]interface{}{"allow_credentials": true, "allow_origins": []interface{}{"https://a.com"}}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// 只用校验函数时不允许所有来源
+	r := newCorsEngine(WithAllowOriginFunc(func(origin string) bool { return origin == "https://a.com" }), WithAllowCredentials(true))
+	w := corsRequest(r, http.MethodGet, "https://evil.com", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expect disallowed origin, got %v", w.Header())
+	}
+	w = corsRequest(r, http.MethodGet, "https://a.com", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://a.com" || w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("unexpected cors headers %v", w.Header())
+	}
+}
@@ -0,0 +1,146 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/errors/report"
+)
+
+// RequestIDHeader 请求id, 没有时使用链路的 trace id
+const RequestIDHeader = "X-Request-Id"
+
+// ErrorResponse 统一的错误返回格式
+type ErrorResponse struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	Reference string `json:"reference,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	// Fields 参数校验失败的字段, 见 errors.WithViolations
+	Fields []errors.FieldViolation `json:"fields,omitempty"`
+}
+
+// NewErrorResponse 根据 errors.ParseCoder 生成错误返回和 http 状态码,
+// 未注册的错误码统一返回 unknown coder 的对外信息, 不暴露内部错误;
+// 对外信息按 Accept-Language 从 errors.RegisterMessages 注册的目录中取, 没有时使用注册的英文信息
+func NewErrorResponse(c *gin.Context, err error) (int, *ErrorResponse) {
+	coder := errors.ParseCoder(err)
+	httpStatus := coder.HTTPStatus()
+	if httpStatus < http.StatusBadRequest || httpStatus > 599 {
+		httpStatus = http.StatusInternalServerError
+	}
+	message := errors.LocalizedString(coder, errors.ParseAcceptLanguage(c.GetHeader("Accept-Language"))...)
+	if message == "" {
+		message = http.StatusText(httpStatus)
+	}
+	return httpStatus, &ErrorResponse{
+		Code:      coder.Code(),
+		Message:   message,
+		Reference: coder.Reference(),
+		RequestID: RequestID(c),
+		Fields:    errors.Violations(err),
+	}
+}
+
+// Render 把错误按照统一格式写回, Accept 为 protobuf 时返回 google.rpc.Status, 否则返回 json,
+// 5xx 错误上报到 report.Default()
+func Render(c *gin.Context, err error) {
+	if errors.ParseCoder(err).HTTPStatus() >= http.StatusInternalServerError {
+		report.Error(c.Request.Context(), err, operation(c), c.Request.Header)
+	}
+	render(c, err)
+}
+
+func render(c *gin.Context, err error) {
+	httpStatus, resp := NewErrorResponse(c, err)
+	if resp.RequestID != "" {
+		c.Header(RequestIDHeader, resp.RequestID)
+	}
+	if acceptProtobuf(c.GetHeader("Accept")) {
+		c.ProtoBuf(httpStatus, resp.proto(httpStatus))
+		return
+	}
+	c.JSON(httpStatus, resp)
+}
+
+// ErrorHandler handler 通过 c.Error 记录错误且没有写返回时, 使用 Render 统一返回最后一个错误
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		Render(c, c.Errors.Last().Err)
+	}
+}
+
+// RequestID 优先取请求头 X-Request-Id, 其次是链路 trace id
+func RequestID(c *gin.Context) string {
+	if id := c.GetHeader(RequestIDHeader); id != "" {
+		return id
+	}
+	if sc := trace.SpanContextFromContext(c.Request.Context()); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+// operation 路由模板, 没有匹配到路由时使用请求路径
+func operation(c *gin.Context) string {
+	if p := c.FullPath(); p != "" {
+		return c.Request.Method + " " + p
+	}
+	return c.Request.Method + " " + c.Request.URL.Path
+}
+
+func acceptProtobuf(accept string) bool {
+	for _, v := range strings.Split(accept, ",") {
+		mime := strings.TrimSpace(strings.SplitN(v, ";", 2)[0])
+		if mime == binding.MIMEPROTOBUF || mime == "application/protobuf" {
+			return true
+		}
+	}
+	return false
+}
+
+// proto 编码为 google.rpc.Status, lori 错误码等信息放在 ErrorInfo 中, 和 grpc 保持一致
+func (r *ErrorResponse) proto(httpStatus int) *spb.Status {
+	st := &spb.Status{
+		Code:    int32(errors.GRPCCodeFromHTTP(httpStatus)),
+		Message: r.Message,
+	}
+	info, err := anypb.New(&errdetails.ErrorInfo{
+		Reason: strconv.Itoa(r.Code),
+		Domain: errors.ErrorInfoDomain,
+		Metadata: map[string]string{
+			"code":       strconv.Itoa(r.Code),
+			"reference":  r.Reference,
+			"request_id": r.RequestID,
+		},
+	})
+	if err == nil {
+		st.Details = append(st.Details, info)
+	}
+	if len(r.Fields) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, f := range r.Fields {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       f.Field,
+				Description: f.Description,
+			})
+		}
+		if detail, err := anypb.New(br); err == nil {
+			st.Details = append(st.Details, detail)
+		}
+	}
+	return st
+}
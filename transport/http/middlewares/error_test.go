@@ -0,0 +1,113 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cr-mao/lori/errors"
+)
+
+const errOrderNotFound = 130404
+
+type orderCoder struct{}
+
+func (orderCoder) Code() int         { return errOrderNotFound }
+func (orderCoder) HTTPStatus() int   { return http.StatusNotFound }
+func (orderCoder) String() string    { return "order not found" }
+func (orderCoder) Reference() string { return "http://doc/order" }
+
+func newErrorEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Recovery(), ErrorHandler())
+	r.GET("/order", func(c *gin.Context) {
+		_ = c.Error(errors.WithCode(errOrderNotFound, "order %d not found in db", 1))
+	})
+	r.GET("/internal", func(c *gin.Context) {
+		_ = c.Error(errors.New("dial tcp 10.0.0.1:3306: connection refused"))
+	})
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	return r
+}
+
+func TestRender(t *testing.T) {
+	errors.Register(orderCoder{})
+	r := newErrorEngine()
+
+	tests := []struct {
+		path   string
+		status int
+		code   int
+		msg    string
+	}{
+		{"/order", http.StatusNotFound, errOrderNotFound, "order not found"},
+		{"/internal", http.StatusInternalServerError, 1, "An internal server error occurred"},
+		{"/panic", http.StatusInternalServerError, 1, "An internal server error occurred"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set(RequestIDHeader, "req-1")
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expect status %d, got %d", tt.path, tt.status, w.Code)
+		}
+		var resp ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		if resp.Code != tt.code || resp.Message != tt.msg || resp.RequestID != "req-1" {
+			t.Errorf("%s: unexpected response %+v", tt.path, resp)
+		}
+	}
+}
+
+func TestRenderLocalized(t *testing.T) {
+	errors.Register(orderCoder{})
+	errors.RegisterMessages("zh-CN", map[int]string{errOrderNotFound: "订单不存在"})
+	r := newErrorEngine()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/order", nil)
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
+	r.ServeHTTP(w, req)
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message != "订单不存在" {
+		t.Errorf("expect localized message, got %q", resp.Message)
+	}
+}
+
+func TestRenderProtobuf(t *testing.T) {
+	errors.Register(orderCoder{})
+	r := newErrorEngine()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/order", nil)
+	req.Header.Set("Accept", "application/x-protobuf")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expect status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	var st spb.Status
+	if err := proto.Unmarshal(w.Body.Bytes(), &st); err != nil {
+		t.Fatal(err)
+	}
+	if codes.Code(st.Code) != codes.NotFound || st.Message != "order not found" || len(st.Details) != 1 {
+		t.Errorf("unexpected status %+v", &st)
+	}
+}
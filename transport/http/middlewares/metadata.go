@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/cr-mao/lori/metadata"
+	"github.com/cr-mao/lori/transport"
+)
+
+// Metadata 从请求头中提取 x-md- 开头的 key, 放入 metadata.FromServerContext(c.Request.Context())
+func Metadata(prefixes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var header transport.Header = transport.HTTPHeaderCarrier(c.Request.Header)
+		if tr, ok := transport.FromServerContext(ctx); ok {
+			header = tr.RequestHeader()
+		}
+		c.Request = c.Request.WithContext(metadata.Extract(ctx, header, prefixes...))
+		c.Next()
+	}
+}
@@ -6,12 +6,18 @@ import (
 
 // 外部可以用
 const (
-	RECOVERY string = "recovery"
-	CORS     string = "cors"
+	RECOVERY  string = "recovery"
+	CORS      string = "cors"
+	RATELIMIT string = "ratelimit"
 )
 
+// Middlewares 旧的具名中间件表, 名字没有通过 Register 注册时才会查找
+//
+// Deprecated: 使用 Register 注册 Factory
 var Middlewares = map[string]gin.HandlerFunc{
 	"recovery": Recovery(), //
 	"cors":     Cors(),
+	// bbr 自适应限流，超过负载返回429
+	"ratelimit": defaultRateLimit(),
 	//"logger":   gin.Logger(), // gin的logger ,还是一定要让用户自己外面传才好。
 }
@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/ratelimit"
+	"github.com/cr-mao/lori/ratelimit/bbr"
+)
+
+// RateLimit 限流中间件, 先按路由模板 (c.FullPath()) 匹配静态限流, 再经过全局限流器 (如 bbr),
+// 被拒绝的请求按统一格式返回 ratelimit.ErrRateLimited (429)
+func RateLimit(limiter ratelimit.Limiter, rules ratelimit.Rules) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		done, err := ratelimit.Allow(rules.Match(c.FullPath()), limiter)
+		if err != nil {
+			c.Abort()
+			Render(c, errors.WrapC(err, ratelimit.ErrRateLimited, "%s rate limited", operation(c)))
+			return
+		}
+		// handler panic 时也要回调 done, 否则在途请求数不会减少
+		finished := false
+		defer func() {
+			if !finished {
+				done(ratelimit.DoneInfo{Err: ratelimit.ErrPanic})
+			}
+		}()
+		c.Next()
+		finished = true
+		var di ratelimit.DoneInfo
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			di.Err = c.Errors.Last()
+		}
+		done(di)
+	}
+}
+
+// 命名中间件使用的默认 bbr 限流器, 第一次请求时才创建, 避免没用到也去采样 cpu
+func defaultRateLimit(opts ...bbr.Option) gin.HandlerFunc {
+	var (
+		once sync.Once
+		h    gin.HandlerFunc
+	)
+	return func(c *gin.Context) {
+		once.Do(func() {
+			h = RateLimit(bbr.NewLimiter(opts...), nil)
+		})
+		h(c)
+	}
+}
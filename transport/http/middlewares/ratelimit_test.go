@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cr-mao/lori/ratelimit"
+)
+
+// countLimiter 记录在途请求数, limit 为 0 时拒绝所有请求
+type countLimiter struct {
+	limit    int
+	inFlight int
+	errs     []error
+}
+
+func (l *countLimiter) Allow() (ratelimit.DoneFunc, error) {
+	if l.inFlight >= l.limit {
+		return nil, ratelimit.ErrLimitExceed
+	}
+	l.inFlight++
+	return func(di ratelimit.DoneInfo) {
+		l.inFlight--
+		l.errs = append(l.errs, di.Err)
+	}, nil
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := &countLimiter{limit: 1}
+	r := gin.New()
+	r.Use(Recovery(), RateLimit(limiter, nil))
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	r.GET("/user", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError || limiter.inFlight != 0 ||
+		len(limiter.errs) != 1 || limiter.errs[0] != ratelimit.ErrPanic {
+		t.Errorf("expect done called on panic, got %d %d %v", w.Code, limiter.inFlight, limiter.errs)
+	}
+
+	limiter.limit = 0
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user", nil))
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusTooManyRequests || resp.Code != ratelimit.ErrRateLimited {
+		t.Errorf("unexpected rate limited response %d %s", w.Code, w.Body.String())
+	}
+}
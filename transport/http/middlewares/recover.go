@@ -2,17 +2,19 @@ package middlewares
 
 import (
 	"net"
-	"net/http"
 	"net/http/httputil"
 	"os"
+	"runtime/debug"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/errors/report"
 	"github.com/cr-mao/lori/log"
 )
 
-// Recovery  来记录 Panic 和 call stack
+// Recovery  来记录 Panic 和 call stack, panic 上报到 report.Default()
 func Recovery() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
@@ -37,11 +39,12 @@ func Recovery() gin.HandlerFunc {
 					// 链接已断开，无法写状态码
 					return
 				}
-				log.Errorf("recovery from panic,err:%+v,request:%s,stacktrace:%s", c.Request.URL.Path, err, string(httpRequest))
-				// 返回 500 状态码
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-					"message": "服务器内部错误，请稍后再试",
-				})
+				stack := debug.Stack()
+				log.Errorf("recovery from panic,urlpath:%s,err:%+v,request:%s,stacktrace:%s", c.Request.URL.Path, err, string(httpRequest), stack)
+				report.Panic(c.Request.Context(), err, stack, operation(c), c.Request.Header)
+				// 返回 500 状态码, 使用统一的错误格式, 不暴露 panic 信息
+				c.Abort()
+				render(c, errors.Errorf("panic: %v", err))
 			}
 		}()
 		c.Next()
@@ -0,0 +1,201 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cr-mao/lori/ratelimit/bbr"
+)
+
+// Factory 根据配置参数创建具名中间件, 参数不合法时返回错误
+type Factory func(options map[string]interface{}) (gin.HandlerFunc, error)
+
+// Config 具名中间件配置, 可以直接从配置文件解析
+type Config struct {
+	Name string `json:"name" yaml:"name"`
+	// Order 小的先执行, 相同时按声明顺序
+	Order   int                    `json:"order" yaml:"order"`
+	Options map[string]interface{} `json:"options" yaml:"options"`
+}
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+)
+
+func init() {
+	Register(RECOVERY, recoveryFactory)
+	Register(CORS, corsFactory)
+	Register(RATELIMIT, rateLimitFactory)
+}
+
+// Register 注册具名中间件, 同名时覆盖, 可以用来替换内置中间件
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = f
+}
+
+// Build 按声明顺序创建中间件, 名字未注册、重复或者参数不合法时返回错误
+func Build(configs ...Config) ([]gin.HandlerFunc, error) {
+	handlers := make([]gin.HandlerFunc, 0, len(configs))
+	seen := make(map[string]struct{}, len(configs))
+	for _, cfg := range configs {
+		if _, ok := seen[cfg.Name]; ok {
+			return nil, fmt.Errorf("middleware %q is declared more than once", cfg.Name)
+		}
+		seen[cfg.Name] = struct{}{}
+		h, err := build(cfg)
+		if err != nil {
+			return nil, err
+		}
+		handlers = append(handlers, h)
+	}
+	return handlers, nil
+}
+
+// Use 按 Order 排序后安装到路由组, 用于给不同的路由组配置不同的中间件
+func Use(r gin.IRoutes, configs ...Config) error {
+	configs = SortConfigs(configs)
+	handlers, err := Build(configs...)
+	if err != nil {
+		return err
+	}
+	r.Use(handlers...)
+	return nil
+}
+
+// SortConfigs 按 Order 稳定排序, 返回新的切片
+func SortConfigs(configs []Config) []Config {
+	sorted := append([]Config(nil), configs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Order < sorted[j].Order
+	})
+	return sorted
+}
+
+func build(cfg Config) (gin.HandlerFunc, error) {
+	mu.RLock()
+	f, ok := factories[cfg.Name]
+	mu.RUnlock()
+	if !ok {
+		// 兼容直接修改 Middlewares 的用法
+		if h, ok := Middlewares[cfg.Name]; ok && len(cfg.Options) == 0 {
+			return h, nil
+		}
+		return nil, fmt.Errorf("middleware %q is not registered", cfg.Name)
+	}
+	h, err := f(cfg.Options)
+	if err != nil {
+		return nil, fmt.Errorf("middleware %q: %w", cfg.Name, err)
+	}
+	return h, nil
+}
+
+// DecodeOptions 配置参数解码到结构体(按 json tag), 未知的参数返回错误, 自定义 Factory 可以使用
+func DecodeOptions(options map[string]interface{}, v interface{}) error {
+	if len(options) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// Duration 配置中的时间, 支持 "10s" 这样的字符串或者秒数
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch value := v.(type) {
+	case float64:
+		*d = Duration(value * float64(time.Second))
+	case string:
+		dur, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*d = Duration(dur)
+	default:
+		return fmt.Errorf("invalid duration %s", b)
+	}
+	return nil
+}
+
+func recoveryFactory(options map[string]interface{}) (gin.HandlerFunc, error) {
+	if err := DecodeOptions(options, &struct{}{}); err != nil {
+		return nil, err
+	}
+	return Recovery(), nil
+}
+
+// corsFactory allow_credentials 为 true 时必须配置 allow_origins, 且不能包含 "*"
+func corsFactory(options map[string]interface{}) (gin.HandlerFunc, error) {
+	var c struct {
+		AllowOrigins        []string `json:"allow_origins"`
+		AllowMethods        []string `json:"allow_methods"`
+		AllowHeaders        []string `json:"allow_headers"`
+		ExposeHeaders       []string `json:"expose_headers"`
+		AllowCredentials    bool     `json:"allow_credentials"`
+		MaxAge              Duration `json:"max_age"`
+		AllowPrivateNetwork bool     `json:"allow_private_network"`
+	}
+	if err := DecodeOptions(options, &c); err != nil {
+		return nil, err
+	}
+	var opts []CorsOption
+	if len(c.AllowOrigins) > 0 {
+		opts = append(opts, WithAllowOrigins(c.AllowOrigins...))
+	}
+	if len(c.AllowMethods) > 0 {
+		opts = append(opts, WithAllowMethods(c.AllowMethods...))
+	}
+	if len(c.AllowHeaders) > 0 {
+		opts = append(opts, WithAllowHeaders(c.AllowHeaders...))
+	}
+	opts = append(opts,
+		WithExposeHeaders(c.ExposeHeaders...),
+		WithAllowCredentials(c.AllowCredentials),
+		WithMaxAge(time.Duration(c.MaxAge)),
+		WithAllowPrivateNetwork(c.AllowPrivateNetwork),
+	)
+	if err := newCorsOptions(opts...).validate(); err != nil {
+		return nil, err
+	}
+	return Cors(opts...), nil
+}
+
+func rateLimitFactory(options map[string]interface{}) (gin.HandlerFunc, error) {
+	var c struct {
+		Window       Duration `json:"window"`
+		Bucket       int      `json:"bucket"`
+		CPUThreshold int64    `json:"cpu_threshold"`
+	}
+	if err := DecodeOptions(options, &c); err != nil {
+		return nil, err
+	}
+	var opts []bbr.Option
+	if c.Window > 0 {
+		opts = append(opts, bbr.WithWindow(time.Duration(c.Window)))
+	}
+	if c.Bucket > 0 {
+		opts = append(opts, bbr.WithBucket(c.Bucket))
+	}
+	if c.CPUThreshold > 0 {
+		opts = append(opts, bbr.WithCPUThreshold(c.CPUThreshold))
+	}
+	return defaultRateLimit(opts...), nil
+}
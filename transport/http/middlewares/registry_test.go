@@ -0,0 +1,100 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []Config
+		err     string
+	}{
+		{"builtin", []Config{{Name: RECOVERY}, {Name: CORS}, {Name: RATELIMIT}}, ""},
+		{"unknown", []Config{{Name: "nope"}}, "not registered"},
+		{"duplicate", []Config{{Name: CORS}, {Name: CORS}}, "more than once"},
+		{"unknown option", []Config{{Name: CORS, Options: map[string]interface{}{"allow_origin": "*"}}}, "unknown field"},
+		{"bad duration", []Config{{Name: CORS, Options: map[string]interface{}{"max_age": "ten"}}}, "invalid duration"},
+		{"options", []Config{{Name: RATELIMIT, Options: map[string]interface{}{"window": "5s", "cpu_threshold": 900}}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers, err := Build(tt.configs...)
+			if tt.err == "" {
+				if err != nil || len(handlers) != len(tt.configs) {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expect error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestUse(t *testing.T) {
+	var order []string
+	Register("test-tag", func(options map[string]interface{}) (gin.HandlerFunc, error) {
+		var c struct {
+			Tag string `json:"tag"`
+		}
+		if err := DecodeOptions(options, &c); err != nil {
+			return nil, err
+		}
+		return func(*gin.Context) {
+			order = append(order, c.Tag)
+		}, nil
+	})
+	Register("test-first", func(map[string]interface{}) (gin.HandlerFunc, error) {
+		return func(*gin.Context) {
+			order = append(order, "first")
+		}, nil
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	g := r.Group("/api")
+	err := Use(g,
+		Config{Name: "test-tag", Order: 10, Options: map[string]interface{}{"tag": "second"}},
+		Config{Name: "test-first", Order: 1},
+		Config{Name: CORS, Order: 10, Options: map[string]interface{}{
+			"allow_origins":     []interface{}{"https://*.example.com"},
+			"allow_credentials": true,
+			"max_age":           600,
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	r.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ping", nil)
+	req.Header.Set("Origin", "https://a.example.com")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("unexpected order %v", order)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://a.example.com" {
+		t.Errorf("expect cors options applied, got %v", w.Header())
+	}
+
+	// 路由组之外的路由不受影响
+	order = nil
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	if len(order) != 0 {
+		t.Errorf("expect group middleware not applied, got %v", order)
+	}
+}
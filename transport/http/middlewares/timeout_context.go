@@ -3,28 +3,140 @@ package middlewares
 import (
 	"context"
 	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/cr-mao/lori/errors"
+	"github.com/cr-mao/lori/log"
 )
 
-// 为每个请求添加上下文
-// 超时控制中间件
+// RequestTimeoutHeader 调用方剩余的超时时间, 毫秒数(1500) 或者 go duration(1.5s)
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// ErrRequestTimeout 请求超时, 返回 504
+const ErrRequestTimeout = 100004
+
+func init() {
+	errors.Register(errors.NewReasonCoder(ErrRequestTimeout, http.StatusGatewayTimeout, "REQUEST_TIMEOUT", "Request timeout", ""))
+}
+
+// TimeoutMiddleware 超时控制中间件, 超时时间取 timeout 和调用方 X-Request-Timeout 中较小的一个,
+// 调用方剩余时间已经用完的请求直接返回 504, 不再执行handler.
+//
+// handler 在单独的 goroutine 中执行, 输出先写到缓冲区, 正常结束后再写回;
+// 超时立即返回 ErrRequestTimeout(504, 带 Connection: close), handler 之后的写入被丢弃.
+// gin.Context 会被复用, 所以中间件返回前仍然等待 handler 结束, handler 需要在 ctx 取消后尽快返回;
+// handler 调用 Flush 后为流式返回, 超时只取消 ctx, 不再写 504
 func TimeoutMiddleware(timeout time.Duration) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		// 用超时context wrap request的context
-		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
-		defer func() {
-			// 检查是否超时
-			if ctx.Err() == context.DeadlineExceeded {
-				c.Writer.WriteHeader(http.StatusGatewayTimeout)
+		d := timeout
+		if budget, ok := ParseRequestTimeout(c.GetHeader(RequestTimeoutHeader)); ok {
+			if budget <= 0 {
 				c.Abort()
+				render(c, errors.WithCode(ErrRequestTimeout, "request deadline already exceeded"))
+				return
 			}
-			//清理资源
-			cancel()
-		}()
-		// 替换
+			if d <= 0 || budget < d {
+				d = budget
+			}
+		}
+		if d <= 0 {
+			c.Next()
+			return
+		}
+		// 用超时context wrap request的context
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
 		c.Request = c.Request.WithContext(ctx)
-		c.Next()
+
+		w := c.Writer
+		tw := newTimeoutWriter(w)
+		// 超时时用来写 504 的 context, handler 所在的 goroutine 还在使用 c
+		cp := c.Copy()
+		c.Writer = tw
+
+		finish := make(chan *panicInfo, 1)
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					finish <- &panicInfo{value: r, stack: debug.Stack()}
+				}
+			}()
+			c.Next()
+			finish <- nil
+		}()
+
+		var p *panicInfo
+		select {
+		case p = <-finish:
+			if p == nil {
+				tw.commit()
+			}
+		case <-ctx.Done():
+			streaming := tw.timeout()
+			// 客户端断开时不用返回
+			if !streaming && ctx.Err() == context.DeadlineExceeded {
+				writeTimeout(cp, w, d)
+			}
+			p = <-finish
+		}
+		c.Writer = w
+		if p != nil {
+			// 没有超时时交给外层的 Recovery 处理, 超时后已经返回了 504;
+			// 超时后 c.JSON 等写入失败会 panic(http.ErrHandlerTimeout), 忽略
+			if !tw.timedOut {
+				panic(p.value)
+			}
+			if p.value != http.ErrHandlerTimeout {
+				log.Errorf("panic in timeout handler: %v\n%s", p.value, p.stack)
+			}
+		}
+		if tw.timedOut {
+			c.Abort()
+		}
+	}
+}
+
+// writeTimeout 写回 504, 带 Content-Length 和 Connection: close, 客户端不用等 handler 结束
+func writeTimeout(cp *gin.Context, w gin.ResponseWriter, d time.Duration) {
+	ew := newTimeoutWriter(w)
+	cp.Writer = ew
+	render(cp, errors.WithCode(ErrRequestTimeout, "request timeout after %s", d))
+	ew.header.Set("Content-Length", strconv.Itoa(ew.buf.Len()))
+	ew.header.Set("Connection", "close")
+	ew.commit()
+	w.Flush()
+}
+
+type panicInfo struct {
+	value interface{}
+	stack []byte
+}
+
+// ParseRequestTimeout 解析 X-Request-Timeout, 支持毫秒数和 go duration 两种格式
+func ParseRequestTimeout(v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return d, true
+	}
+	return 0, false
+}
+
+// FormatRequestTimeout 把剩余时间格式化为 X-Request-Timeout 的值(毫秒), 不足 1 毫秒的部分向上取整,
+// 避免还有剩余时间的请求被下游当作已经超时
+func FormatRequestTimeout(d time.Duration) string {
+	if d <= 0 {
+		return "0"
 	}
+	return strconv.FormatInt(int64((d+time.Millisecond-1)/time.Millisecond), 10)
 }
@@ -0,0 +1,123 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cr-mao/lori/errors"
+)
+
+func newTimeoutEngine(late chan error) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Recovery(), ErrorHandler(), TimeoutMiddleware(50*time.Millisecond))
+	r.GET("/fast", func(c *gin.Context) {
+		c.Header("X-Lori", "fast")
+		c.String(http.StatusCreated, "ok")
+	})
+	r.GET("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		time.Sleep(20 * time.Millisecond)
+		_, err := c.Writer.WriteString("late")
+		late <- err
+	})
+	r.GET("/error", func(c *gin.Context) {
+		_ = c.Error(errors.WithCode(errOrderNotFound, "order not found"))
+	})
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	r.GET("/stream", func(c *gin.Context) {
+		for i := 0; i < 3; i++ {
+			c.SSEvent("tick", i)
+			c.Writer.Flush()
+			time.Sleep(30 * time.Millisecond)
+		}
+	})
+	return r
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	errors.Register(orderCoder{})
+	late := make(chan error, 1)
+	r := newTimeoutEngine(late)
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := serve("/fast")
+	if w.Code != http.StatusCreated || w.Body.String() != "ok" || w.Header().Get("X-Lori") != "fast" {
+		t.Errorf("unexpected fast response %d %s %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	w = serve("/slow")
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusGatewayTimeout || resp.Code != ErrRequestTimeout {
+		t.Errorf("expect coded 504, got %d %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Connection") != "close" || strings.Contains(w.Body.String(), "late") {
+		t.Errorf("unexpected timeout response %v %s", w.Header(), w.Body.String())
+	}
+	if err := <-late; err != http.ErrHandlerTimeout {
+		t.Errorf("expect late write discarded, got %v", err)
+	}
+
+	if w = serve("/error"); w.Code != http.StatusNotFound {
+		t.Errorf("expect error rendered by ErrorHandler, got %d %s", w.Code, w.Body.String())
+	}
+	if w = serve("/panic"); w.Code != http.StatusInternalServerError {
+		t.Errorf("expect panic recovered, got %d %s", w.Code, w.Body.String())
+	}
+	// 流式返回不受缓冲影响, 超时之后不再写 504
+	if w = serve("/stream"); w.Code != http.StatusOK || strings.Count(w.Body.String(), "event:tick") != 3 {
+		t.Errorf("unexpected stream response %d %q", w.Code, w.Body.String())
+	}
+}
+
+// 超时后客户端立即拿到 504, 不用等 handler 结束
+func TestTimeoutMiddleware_ReturnImmediately(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler(), TimeoutMiddleware(50*time.Millisecond))
+	r.GET("/slow", func(c *gin.Context) {
+		time.Sleep(500 * time.Millisecond)
+		c.String(http.StatusOK, "late")
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	start := time.Now()
+	resp, err := http.Get(srv.URL + "/slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGatewayTimeout || time.Since(start) > 300*time.Millisecond {
+		t.Errorf("expect 504 before handler returns, got %d %s after %v", resp.StatusCode, body, time.Since(start))
+	}
+}
+
+func TestFormatRequestTimeout(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		-time.Millisecond:       "0",
+		0:                       "0",
+		time.Microsecond:        "1",
+		time.Millisecond:        "1",
+		1500 * time.Microsecond: "2",
+		time.Second:             "1000",
+	} {
+		if got := FormatRequestTimeout(d); got != want {
+			t.Errorf("%v: expect %s, got %s", d, want, got)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package middlewares
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const noWritten = -1
+
+var _ gin.ResponseWriter = (*timeoutWriter)(nil)
+
+// timeoutWriter 超时中间件使用的 ResponseWriter, handler 的输出先写到缓冲区, handler 正常结束后再写回;
+// 超时之后的写入直接丢弃, 返回 http.ErrHandlerTimeout;
+// handler 调用 Flush 或者 Hijack 后切换为直写模式, 流式返回(SSE, c.Stream)照常工作, 只通过 ctx 感知超时
+type timeoutWriter struct {
+	w gin.ResponseWriter
+
+	mu          sync.Mutex
+	header      http.Header
+	buf         bytes.Buffer
+	code        int
+	size        int
+	wroteHeader bool
+	timedOut    bool
+	streaming   bool // 已经 Flush 或 Hijack, 写入直接交给 w
+}
+
+func newTimeoutWriter(w gin.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{
+		w:      w,
+		header: w.Header().Clone(),
+		code:   http.StatusOK,
+		size:   noWritten,
+	}
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.streaming {
+		return tw.w.Header()
+	}
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || code <= 0 {
+		return
+	}
+	if tw.streaming {
+		tw.w.WriteHeader(code)
+		return
+	}
+	// 和 gin 一样, body 写入之前可以覆盖状态码
+	if tw.size == noWritten {
+		tw.code = code
+	}
+}
+
+func (tw *timeoutWriter) WriteHeaderNow() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if tw.streaming {
+		tw.w.WriteHeaderNow()
+		return
+	}
+	tw.wroteHeader = true
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.streaming {
+		return tw.w.Write(b)
+	}
+	if tw.size == noWritten {
+		tw.size = 0
+	}
+	n, err := tw.buf.Write(b)
+	tw.size += n
+	return n, err
+}
+
+func (tw *timeoutWriter) WriteString(s string) (int, error) {
+	return tw.Write([]byte(s))
+}
+
+func (tw *timeoutWriter) Status() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.streaming {
+		return tw.w.Status()
+	}
+	return tw.code
+}
+
+func (tw *timeoutWriter) Size() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.streaming {
+		return tw.w.Size()
+	}
+	return tw.size
+}
+
+func (tw *timeoutWriter) Written() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.streaming {
+		return tw.w.Written()
+	}
+	return tw.wroteHeader || tw.size != noWritten
+}
+
+// Flush 写回缓冲区并切换为直写模式
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	tw.commitLocked()
+	tw.streaming = true
+	tw.w.Flush()
+}
+
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	tw.streaming = true
+	return tw.w.Hijack()
+}
+
+func (tw *timeoutWriter) CloseNotify() <-chan bool {
+	return tw.w.CloseNotify()
+}
+
+func (tw *timeoutWriter) Pusher() http.Pusher {
+	return tw.w.Pusher()
+}
+
+// commit handler 正常结束后写回, 什么都没写时不写回, 交给 ErrorHandler 处理 c.Errors
+func (tw *timeoutWriter) commit() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.commitLocked()
+}
+
+func (tw *timeoutWriter) commitLocked() {
+	if tw.streaming || (!tw.wroteHeader && tw.size == noWritten) {
+		return
+	}
+	dst := tw.w.Header()
+	for k := range dst {
+		if _, ok := tw.header[k]; !ok {
+			delete(dst, k)
+		}
+	}
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	tw.w.WriteHeader(tw.code)
+	if tw.size == noWritten {
+		tw.w.WriteHeaderNow()
+		return
+	}
+	_, _ = tw.w.Write(tw.buf.Bytes())
+}
+
+// timeout 标记超时, 返回是否已经开始流式返回, 流式返回时无法再写 504, 也不丢弃之后的写入
+func (tw *timeoutWriter) timeout() (streaming bool) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.streaming {
+		tw.timedOut = true
+	}
+	return tw.streaming
+}
@@ -5,6 +5,9 @@ import (
 	"time"
 
 	"github.com/cr-mao/lori/metric"
+	"github.com/cr-mao/lori/middleware"
+	"github.com/cr-mao/lori/ratelimit"
+	mids "github.com/cr-mao/lori/transport/http/middlewares"
 )
 
 type ServerOption func(*Server)
@@ -15,6 +18,7 @@ func WithAddress(addr string) ServerOption {
 	}
 }
 
+// WithTimeout 服务端处理请求的超时时间, 默认 5 秒, <=0 时只使用调用方的 X-Request-Timeout
 func WithTimeout(timeout time.Duration) ServerOption {
 	return func(s *Server) {
 		s.timeout = timeout
@@ -46,9 +50,20 @@ func WithServiceName(srvName string) ServerOption {
 	}
 }
 
+// WithMiddlewares 按名字安装具名中间件, 按声明顺序在内置中间件之前执行, 需要参数或者排序时使用 WithMiddlewareConfigs
 func WithMiddlewares(middlewares []string) ServerOption {
 	return func(s *Server) {
-		s.middlewares = middlewares
+		for _, name := range middlewares {
+			s.middlewares = append(s.middlewares, mids.Config{Name: name})
+		}
+	}
+}
+
+// WithMiddlewareConfigs 带参数的具名中间件, Order 决定和内置中间件的先后, 见 OrderErrorHandler 等;
+// 名字未注册或者参数不合法时 Start 返回错误
+func WithMiddlewareConfigs(configs ...mids.Config) ServerOption {
+	return func(s *Server) {
+		s.middlewares = append(s.middlewares, configs...)
 	}
 }
 
@@ -57,3 +72,33 @@ func WithMetric(metric metric.GinMetric) ServerOption {
 		s.metric = metric
 	}
 }
+
+// WithLimiter 设置全局限流器, 比如 bbr.NewLimiter(), 被拒绝的请求返回 429
+func WithLimiter(limiter ratelimit.Limiter) ServerOption {
+	return func(s *Server) {
+		s.limiter = limiter
+	}
+}
+
+// WithLimitRules 设置路由级别的静态限流, key 为 gin 路由模板, 如 /v1/user/:id
+func WithLimitRules(rules ratelimit.Rules) ServerOption {
+	return func(s *Server) {
+		s.limitRules = rules
+	}
+}
+
+// WithMiddleware 与传输层无关的中间件, 在内置中间件之后、路由 handler 之前执行,
+// 可以和 grpc.WithMiddleware 共用同一条链
+func WithMiddleware(m ...middleware.Middleware) ServerOption {
+	return func(s *Server) {
+		s.ms = m
+	}
+}
+
+// WithEnableValidate protoc-gen-lori-http 生成的路由是否校验请求参数, 请求消息实现了 ValidateAll/Validate (protoc-gen-validate) 时调用,
+// 和 grpc.WithEnableValidate 一致
+func WithEnableValidate(enable bool) ServerOption {
+	return func(s *Server) {
+		s.enableValidate = enable
+	}
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cr-mao/lori/internal/endpoint"
+	"github.com/cr-mao/lori/log"
+	"github.com/cr-mao/lori/registry"
+)
+
+// ErrNoAvailable 没有可用的服务实例
+var ErrNoAvailable = errors.New("http client: no available endpoint")
+
+// target 解析后的调用目标
+//
+//	discovery:///user-service -> 服务发现
+//	http://127.0.0.1:8000 或 127.0.0.1:8000 -> 直连
+type target struct {
+	scheme    string
+	authority string
+	name      string
+}
+
+func parseTarget(endpoint string, insecure bool) (*target, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+		if !insecure {
+			endpoint = "https://" + strings.TrimPrefix(endpoint, "http://")
+		}
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &target{scheme: u.Scheme, authority: u.Host, name: strings.TrimPrefix(u.Path, "/")}, nil
+}
+
+// resolver 维护服务实例地址, 轮询选择
+type resolver struct {
+	mu       sync.RWMutex
+	hosts    []string
+	next     uint64
+	insecure bool
+
+	watcher registry.Watcher
+	cancel  context.CancelFunc
+}
+
+// newResolver 服务发现时等待第一次拿到实例列表, 之后在后台监听变化
+func newResolver(ctx context.Context, d registry.Discovery, t *target, insecure bool, timeout time.Duration) (*resolver, error) {
+	r := &resolver{insecure: insecure}
+	if t.scheme != "discovery" {
+		r.hosts = []string{t.authority}
+		return r, nil
+	}
+	if d == nil {
+		return nil, errors.New("http client: discovery target requires WithClientDiscovery")
+	}
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	w, err := d.Watch(watchCtx, t.name)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	r.watcher = w
+	r.cancel = cancel
+
+	first := make(chan error, 1)
+	go func() {
+		ins, err := w.Next()
+		if err == nil {
+			r.update(ins)
+		}
+		first <- err
+	}()
+	ctx, cancelWait := context.WithTimeout(ctx, timeout)
+	defer cancelWait()
+	select {
+	case err = <-first:
+	case <-ctx.Done():
+		err = errors.New("http client: discovery get instances overtime")
+	}
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	go r.watch()
+	return r, nil
+}
+
+func (r *resolver) watch() {
+	for {
+		ins, err := r.watcher.Next()
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			log.Errorf("[http resolver] Failed to watch discovery endpoint: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		r.update(ins)
+	}
+}
+
+func (r *resolver) update(ins []*registry.ServiceInstance) {
+	hosts := make([]string, 0, len(ins))
+	seen := make(map[string]struct{}, len(ins))
+	for _, in := range ins {
+		host, err := endpoint.ParseEndpoint(in.Endpoints, endpoint.Scheme("http", !r.insecure))
+		if err != nil {
+			log.Errorf("[http resolver] Failed to parse discovery endpoint: %v", err)
+			continue
+		}
+		if host == "" {
+			continue
+		}
+		if _, ok := seen[host]; ok {
+			continue
+		}
+		seen[host] = struct{}{}
+		hosts = append(hosts, host)
+	}
+	if len(hosts) == 0 {
+		log.Warnf("[http resolver] Zero endpoint found,refused to write, instances: %v", ins)
+		return
+	}
+	r.mu.Lock()
+	r.hosts = hosts
+	r.mu.Unlock()
+}
+
+// pick 轮询选择一个实例
+func (r *resolver) pick() (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.hosts) == 0 {
+		return "", ErrNoAvailable
+	}
+	n := atomic.AddUint64(&r.next, 1)
+	return r.hosts[(n-1)%uint64(len(r.hosts))], nil
+}
+
+func (r *resolver) Close() error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+	return r.watcher.Stop()
+}
@@ -7,6 +7,8 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,6 +17,8 @@ import (
 	"github.com/cr-mao/lori/internal/host"
 	"github.com/cr-mao/lori/log"
 	"github.com/cr-mao/lori/metric"
+	"github.com/cr-mao/lori/middleware"
+	"github.com/cr-mao/lori/ratelimit"
 	"github.com/cr-mao/lori/transport"
 	mids "github.com/cr-mao/lori/transport/http/middlewares"
 )
@@ -43,14 +47,26 @@ type Server struct {
 	metric metric.GinMetric
 
 	//中间件
-	middlewares []string //传字符串进来， 顺序需要自己定义好
+	middlewares []mids.Config
 
 	//请求超时
 	timeout time.Duration
 
+	//全局限流器(如bbr) 和 路由级别的静态限流
+	limiter    ratelimit.Limiter
+	limitRules ratelimit.Rules
+
+	//与传输层无关的中间件
+	ms []middleware.Middleware
+
+	//protoc-gen-lori-http 生成的路由是否校验请求参数
+	enableValidate bool
+
 	err error
 
 	tlsConf *tls.Config
+
+	handlerOnce sync.Once
 }
 
 func NewServer(opts ...ServerOption) *Server {
@@ -66,33 +82,75 @@ func NewServer(opts ...ServerOption) *Server {
 	for _, o := range opts {
 		o(srv)
 	}
-	for _, m := range srv.middlewares {
-		mw, ok := mids.Middlewares[m]
-		if !ok {
-			log.Warnf("can not find middleware: %s", m)
-			continue
-		}
-		log.Infof("install middleware: %s", m)
-		srv.Use(mw)
-	}
-	//超时中间件
-	if srv.timeout > 0 {
-		srv.Use(mids.TimeoutMiddleware(srv.timeout))
-		log.Infof("install middleware: %s", "timeout")
+	//Transport 放入 context, 需要在其他中间件之前
+	srv.Use(srv.serverTransport())
+	if err := srv.installMiddlewares(); err != nil {
+		log.Errorf("install middleware error: %s", err.Error())
+		srv.err = err
 	}
 
 	//设置开发模式，打印路由信息
 	if srv.mode != gin.DebugMode && srv.mode != gin.ReleaseMode && srv.mode != gin.TestMode {
 		srv.mode = gin.ReleaseMode
 	}
-	//设置开发模式
-	gin.SetMode(srv.mode)
+	//设置开发模式, mode 是 gin 的全局变量, 相同时不重复写入
+	if gin.Mode() != srv.mode {
+		gin.SetMode(srv.mode)
+	}
 	gin.DebugPrintRouteFunc = func(httpMethod, absolutePath, handlerName string, nuHandlers int) {
 		log.Infof("%-6s %-s --> %s(%d handlers)", httpMethod, absolutePath, handlerName, nuHandlers)
 	}
 	return srv
 }
 
+// 内置中间件的顺序, 具名中间件的 Order 默认为 0, 在所有内置中间件之前执行
+const (
+	OrderErrorHandler = 100 //c.Error 记录的错误统一格式返回
+	OrderMetadata     = 200 //x-md- 开头的请求头放入 metadata
+	OrderRateLimit    = 300 //限流
+	OrderTimeout      = 400 //超时
+	OrderMiddleware   = 500 //与传输层无关的中间件, 能拿到超时 ctx 和 handler 的错误
+)
+
+type orderedHandler struct {
+	name    string
+	order   int
+	handler gin.HandlerFunc
+}
+
+// installMiddlewares 具名中间件和内置中间件按 Order 排序后安装, Order 相同时具名中间件在前
+func (s *Server) installMiddlewares() error {
+	configs := mids.SortConfigs(s.middlewares)
+	handlers, err := mids.Build(configs...)
+	if err != nil {
+		return err
+	}
+	chain := make([]orderedHandler, 0, len(handlers)+5)
+	for i, h := range handlers {
+		chain = append(chain, orderedHandler{configs[i].Name, configs[i].Order, h})
+	}
+	chain = append(chain,
+		orderedHandler{"error", OrderErrorHandler, mids.ErrorHandler()},
+		orderedHandler{"metadata", OrderMetadata, mids.Metadata()},
+	)
+	if s.limiter != nil || len(s.limitRules) > 0 {
+		chain = append(chain, orderedHandler{"ratelimit", OrderRateLimit, mids.RateLimit(s.limiter, s.limitRules)})
+	}
+	// timeout <= 0 时不限制服务端超时, 但仍然按调用方的 X-Request-Timeout 截断和拒绝已经超时的请求
+	chain = append(chain, orderedHandler{"timeout", OrderTimeout, mids.TimeoutMiddleware(s.timeout)})
+	if len(s.ms) > 0 {
+		chain = append(chain, orderedHandler{"middleware", OrderMiddleware, s.middlewareHandler()})
+	}
+	sort.SliceStable(chain, func(i, j int) bool {
+		return chain[i].order < chain[j].order
+	})
+	for _, h := range chain {
+		log.Infof("install middleware: %s", h.name)
+		s.Use(h.handler)
+	}
+	return nil
+}
+
 func (s *Server) listenAndEndpoint() error {
 	if s.lis == nil {
 		lis, err := net.Listen(s.network, s.address)
@@ -131,16 +189,12 @@ func (s *Server) Start(ctx context.Context) error {
 	//if s.enableProfiling {
 	//	pprof.Register(s.Engine)
 	//}
-	if s.metric != nil {
-		s.metric.Use(s.Engine)
-	}
-
 	if err := s.listenAndEndpoint(); err != nil {
 		return err
 	}
 	s.server = &http.Server{
 		Addr:      s.address,
-		Handler:   s.Engine,
+		Handler:   s.Handler(),
 		TLSConfig: s.tlsConf,
 	}
 	log.Infof("[HTTP] server listening on: %s", s.lis.Addr().String())
@@ -156,6 +210,21 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
+// Err 返回创建 Server 时的配置错误, 比如中间件未注册
+func (s *Server) Err() error {
+	return s.err
+}
+
+// Handler 返回注册了内置路由(metrics)的 http.Handler, 单端口复用(transport/mux)时由 mux.Server 使用
+func (s *Server) Handler() http.Handler {
+	s.handlerOnce.Do(func() {
+		if s.metric != nil {
+			s.metric.Use(s.Engine)
+		}
+	})
+	return s.Engine
+}
+
 func (s *Server) Stop(ctx context.Context) error {
 	log.Infof("rest server is stopping")
 	if err := s.server.Shutdown(ctx); err != nil {
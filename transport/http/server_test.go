@@ -2,15 +2,125 @@ package http
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/gin-gonic/gin"
+
+	mids "github.com/cr-mao/lori/transport/http/middlewares"
 )
 
+// TestMain gin 的 mode 是全局变量, 在创建任何 Server 之前设置一次, 避免并发读写
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
 func TestServer(t *testing.T) {
+	s := NewServer(WithMode(gin.TestMode), WithAddress("127.0.0.1:0"))
+	s.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	u, err := s.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
 	go func() {
-		s := NewServer(WithAddress("0.0.0.0:8080"))
-		_ = s.Start(context.Background())
+		done <- s.Start(context.Background())
 	}()
 
-	time.Sleep(time.Second * 5)
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = http.Get(u.String() + "/ping"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expect 200, got %d", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err = s.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-done; err != nil {
+		t.Errorf("expect nil after stop, got %v", err)
+	}
+}
+
+func TestServerMiddlewareConfigs(t *testing.T) {
+	deadlines := map[string]bool{}
+	mids.Register("test-deadline", func(options map[string]interface{}) (gin.HandlerFunc, error) {
+		var c struct {
+			Name string `json:"name"`
+		}
+		if err := mids.DecodeOptions(options, &c); err != nil {
+			return nil, err
+		}
+		return func(ctx *gin.Context) {
+			_, ok := ctx.Request.Context().Deadline()
+			deadlines[c.Name] = ok
+		}, nil
+	})
+	s := NewServer(WithMode(gin.TestMode), WithMiddlewareConfigs(
+		mids.Config{Name: "test-deadline", Order: OrderTimeout + 1, Options: map[string]interface{}{"name": "after"}},
+	))
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	s.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	if w.Code != http.StatusOK || !deadlines["after"] {
+		t.Errorf("expect middleware installed after timeout, got %d %v", w.Code, deadlines)
+	}
+
+	s = NewServer(WithMode(gin.TestMode), WithMiddlewares([]string{"recovery", "not-exist"}))
+	if err := s.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "not-exist") {
+		t.Errorf("expect unknown middleware error, got %v", err)
+	}
+}
+
+func TestServerRequestTimeoutWithoutServerTimeout(t *testing.T) {
+	s := NewServer(WithMode(gin.TestMode), WithTimeout(0))
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	var deadline bool
+	s.GET("/ping", func(c *gin.Context) {
+		_, deadline = c.Request.Context().Deadline()
+		c.String(http.StatusOK, "pong")
+	})
+	serve := func(budget string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		if budget != "" {
+			req.Header.Set(mids.RequestTimeoutHeader, budget)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve(""); w.Code != http.StatusOK || deadline {
+		t.Errorf("expect no deadline without budget, got %d %v", w.Code, deadline)
+	}
+	if w := serve("1000"); w.Code != http.StatusOK || !deadline {
+		t.Errorf("expect deadline from caller budget, got %d %v", w.Code, deadline)
+	}
+	if w := serve("0"); w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expect expired request rejected, got %d", w.Code)
+	}
 }
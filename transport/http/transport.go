@@ -4,9 +4,14 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/gin-gonic/gin"
+
 	"github.com/cr-mao/lori/transport"
 )
 
+// TransportKey *gin.Context 中保存 Transporter 的 key, gin 的 c.Value 只支持 string key
+const TransportKey = "lori/transport"
+
 var _ Transporter = (*Transport)(nil)
 
 // Transporter is http Transporter
@@ -80,33 +85,54 @@ func RequestFromServerContext(ctx context.Context) (*http.Request, bool) {
 	return nil, false
 }
 
-type headerCarrier http.Header
-
-// Get returns the value associated with the passed key.
-func (hc headerCarrier) Get(key string) string {
-	return http.Header(hc).Get(key)
-}
-
-// Set stores the key-value pair.
-func (hc headerCarrier) Set(key string, value string) {
-	http.Header(hc).Set(key, value)
-}
-
-// Add append value to key-values pair.
-func (hc headerCarrier) Add(key string, value string) {
-	http.Header(hc).Add(key, value)
+// FromGinContext 获取 gin handler 中的 Transporter, 与 transport.FromServerContext(c.Request.Context()) 相同
+func FromGinContext(c *gin.Context) (Transporter, bool) {
+	if v, ok := c.Get(TransportKey); ok {
+		tr, ok := v.(Transporter)
+		return tr, ok
+	}
+	if tr, ok := transport.FromServerContext(c.Request.Context()); ok {
+		tr, ok := tr.(Transporter)
+		return tr, ok
+	}
+	return nil, false
 }
 
-// Keys lists the keys stored in this carrier.
-func (hc headerCarrier) Keys() []string {
-	keys := make([]string, 0, len(hc))
-	for k := range http.Header(hc) {
-		keys = append(keys, k)
+// serverTransport 内置中间件, 把 Transport 放入 c.Request.Context() 和 *gin.Context,
+// 让 auth, 日志, metadata 等中间件在 http 和 grpc 下行为一致;
+// operation 为路由模板, 比如 /v1/user/:id, 没有匹配到路由时使用请求路径
+func (s *Server) serverTransport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var endpoint string
+		if s.endpoint != nil {
+			endpoint = s.endpoint.String()
+		}
+		op := c.FullPath()
+		if op == "" {
+			op = c.Request.URL.Path
+		}
+		tr := &Transport{
+			endpoint:     endpoint,
+			operation:    op,
+			reqHeader:    headerCarrier(c.Request.Header),
+			replyHeader:  headerCarrier(c.Writer.Header()),
+			request:      c.Request,
+			pathTemplate: c.FullPath(),
+		}
+		c.Request = c.Request.WithContext(transport.NewServerContext(c.Request.Context(), tr))
+		tr.request = c.Request
+		c.Set(TransportKey, tr)
+		if s.enableValidate {
+			c.Set(validateKey, true)
+		}
+		c.Next()
 	}
-	return keys
 }
 
-// Values returns a slice of values associated with the passed key.
-func (hc headerCarrier) Values(key string) []string {
-	return http.Header(hc).Values(key)
+type headerCarrier = transport.HTTPHeaderCarrier
+
+// NewHeaderCarrier 把 http.Header 包装成 transport.Header,
+// 比如发起 http 请求时透传 metadata: metadata.Inject(ctx, NewHeaderCarrier(req.Header))
+func NewHeaderCarrier(h http.Header) transport.Header {
+	return headerCarrier(h)
 }
@@ -3,10 +3,14 @@ package http
 import (
 	"context"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"sort"
 	"testing"
 
+	"github.com/gin-gonic/gin"
+
+	"github.com/cr-mao/lori/metadata"
 	"github.com/cr-mao/lori/transport"
 )
 
@@ -92,3 +96,37 @@ func TestSetOperation(t *testing.T) {
 		t.Errorf("expect %v, got %v", "lori", tr.operation)
 	}
 }
+
+func TestServerTransport(t *testing.T) {
+	s := NewServer(WithMode(gin.TestMode), WithTimeout(0))
+	s.GET("/v1/user/:id", func(c *gin.Context) {
+		tr, ok := transport.FromServerContext(c.Request.Context())
+		if !ok {
+			t.Fatal("expect transport in request context")
+		}
+		gtr, ok := FromGinContext(c)
+		if !ok || gtr != tr {
+			t.Fatal("expect the same transport in gin context")
+		}
+		if tr.Kind() != transport.KindHTTP || tr.Operation() != "/v1/user/:id" || gtr.PathTemplate() != "/v1/user/:id" {
+			t.Errorf("unexpected transport %+v", tr)
+		}
+		if gtr.Request().URL.Path != "/v1/user/1" {
+			t.Errorf("unexpected request path %s", gtr.Request().URL.Path)
+		}
+		if md, _ := metadata.FromServerContext(c.Request.Context()); md.Get("x-md-global-uid") != "1001" {
+			t.Errorf("expect metadata from transport header, got %v", md)
+		}
+		tr.ReplyHeader().Set("X-Lori", tr.RequestHeader().Get("X-Lori"))
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/user/1", nil)
+	req.Header.Set("X-Lori", "ok")
+	req.Header.Set("x-md-global-uid", "1001")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent || w.Header().Get("X-Lori") != "ok" {
+		t.Errorf("unexpected response %d %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,300 @@
+// Package mux grpc 和 http 复用同一个端口:
+// content-type 为 application/grpc 的 HTTP/2 请求交给 grpc.Server, 其他请求交给 gin,
+// 明文时通过 h2c 支持 HTTP/2.
+//
+//	grpcSrv := grpc.NewServer()
+//	httpSrv := http.NewServer()
+//	app := lori.New(lori.WithServer(mux.NewServer(grpcSrv, httpSrv, mux.WithAddress(":8000"))))
+package mux
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc/codes"
+
+	"github.com/cr-mao/lori/internal/endpoint"
+	"github.com/cr-mao/lori/internal/host"
+	"github.com/cr-mao/lori/log"
+	"github.com/cr-mao/lori/transport"
+	"github.com/cr-mao/lori/transport/grpc"
+	lorihttp "github.com/cr-mao/lori/transport/http"
+)
+
+var _ transport.Server = (*Server)(nil)
+var _ transport.MultiEndpointer = (*Server)(nil)
+
+// Server 单端口同时提供 grpc 和 http
+type Server struct {
+	grpc    *grpc.Server
+	http    *lorihttp.Server
+	server  *http.Server
+	lis     net.Listener
+	network string
+	address string
+	tlsConf *tls.Config
+
+	endpoints []*url.URL
+	err       error
+
+	// h2c 的连接被 hijack, http.Server.Shutdown 不会等待其中的请求, 也不会关闭这些连接,
+	// 需要自己计数, 并在 Stop 时关闭
+	active   atomic.Int64
+	draining atomic.Bool
+	connMu   sync.Mutex
+	conns    map[*trackedConn]struct{}
+}
+
+// ServerOption mux server 选项
+type ServerOption func(*Server)
+
+func WithNetwork(network string) ServerOption {
+	return func(s *Server) {
+		s.network = network
+	}
+}
+
+func WithAddress(address string) ServerOption {
+	return func(s *Server) {
+		s.address = address
+	}
+}
+
+// WithListener with server lis
+func WithListener(lis net.Listener) ServerOption {
+	return func(s *Server) {
+		s.lis = lis
+	}
+}
+
+// TLSConfig with TLS config, 通过 ALPN 协商 HTTP/2
+func TLSConfig(c *tls.Config) ServerOption {
+	return func(s *Server) {
+		s.tlsConf = c
+	}
+}
+
+// NewServer grpcSrv 和 httpSrv 只用来处理请求, 它们自己的地址, 监听等配置不再生效
+func NewServer(grpcSrv *grpc.Server, httpSrv *lorihttp.Server, opts ...ServerOption) *Server {
+	s := &Server{
+		grpc:    grpcSrv,
+		http:    httpSrv,
+		network: "tcp",
+		address: ":0",
+		conns:   make(map[*trackedConn]struct{}),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// ServeHTTP 按协议和 content-type 分发, HTTP/2 的请求(grpc 和 h2c 的 http 请求)都计数, Stop 时等待
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor == 2 {
+		s.active.Add(1)
+		defer s.active.Add(-1)
+	}
+	if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		if s.draining.Load() {
+			// 停止中不再接收新的 grpc 请求, 返回 UNAVAILABLE 让客户端重试其他节点
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Grpc-Status", strconv.Itoa(int(codes.Unavailable)))
+			w.Header().Set("Grpc-Message", "server is shutting down")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		s.grpc.ServeHTTP(w, r)
+		return
+	}
+	s.http.Handler().ServeHTTP(w, r)
+}
+
+// Endpoints grpc 和 http 各一个端点, 注册中心中同一个实例可以被两种客户端发现:
+//
+//	grpc://127.0.0.1:8000
+//	http://127.0.0.1:8000
+func (s *Server) Endpoints() ([]*url.URL, error) {
+	if err := s.listenAndEndpoint(); err != nil {
+		return nil, err
+	}
+	return s.endpoints, nil
+}
+
+func (s *Server) listenAndEndpoint() error {
+	if s.lis == nil {
+		lis, err := net.Listen(s.network, s.address)
+		if err != nil {
+			s.err = err
+			return err
+		}
+		s.lis = lis
+	}
+	if s.endpoints == nil {
+		addr, err := host.Extract(s.address, s.lis)
+		if err != nil {
+			s.err = err
+			return err
+		}
+		secure := s.tlsConf != nil
+		s.endpoints = []*url.URL{
+			endpoint.NewEndpoint(endpoint.Scheme("grpc", secure), addr),
+			endpoint.NewEndpoint(endpoint.Scheme("http", secure), addr),
+		}
+	}
+	return s.err
+}
+
+// Start 开始监听, grpc 健康检查设置为 SERVING
+func (s *Server) Start(ctx context.Context) error {
+	if err := s.http.Err(); err != nil {
+		return err
+	}
+	if err := s.listenAndEndpoint(); err != nil {
+		return err
+	}
+	var err error
+	if s.tlsConf != nil {
+		s.server = &http.Server{
+			Handler:   s,
+			TLSConfig: s.tlsConf,
+		}
+	} else {
+		h2s := &http2.Server{}
+		s.server = &http.Server{
+			Handler: h2c.NewHandler(s, h2s),
+		}
+		// 注册 Shutdown 钩子, 停止时给 h2c 连接发送 GOAWAY
+		if err = http2.ConfigureServer(s.server, h2s); err != nil {
+			return err
+		}
+	}
+	s.grpc.Health().Resume()
+	log.Infof("[mux] grpc and http server listening on: %s", s.lis.Addr().String())
+	if s.tlsConf != nil {
+		err = s.server.ServeTLS(s.lis, "", "")
+	} else {
+		err = s.server.Serve(&trackedListener{Listener: s.lis, s: s})
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Stop 健康检查设置为 NOT_SERVING, h2c 连接发送 GOAWAY, 等待 http 和 grpc 请求处理完, 最多等到 ctx 结束;
+// grpc 请求由 http.Server 承载, grpc.GracefulStop 不支持 ServeHTTP, 所以等待处理中的请求结束后调用 grpc.Stop,
+// 最后等待 h2c 连接在发送 GOAWAY 并写完响应后自行关闭, ctx 结束时强制关闭
+func (s *Server) Stop(ctx context.Context) error {
+	log.Info("[mux] server stopping")
+	s.grpc.Health().Shutdown()
+	s.draining.Store(true)
+	var err error
+	if s.server != nil {
+		if err = s.server.Shutdown(ctx); err != nil {
+			log.Errorf("[mux] server shutdown error: %s", err.Error())
+		}
+	}
+	if werr := s.waitRequests(ctx); werr != nil {
+		log.Errorf("[mux] %d HTTP/2 requests still in flight: %s", s.active.Load(), werr.Error())
+		if err == nil {
+			err = werr
+		}
+	}
+	s.grpc.Server.Stop()
+	if werr := s.waitConns(ctx); werr != nil {
+		log.Errorf("[mux] close h2c connections: %s", werr.Error())
+		if err == nil {
+			err = werr
+		}
+	}
+	return err
+}
+
+// waitRequests 等待处理中的 HTTP/2 请求结束, 和 http.Server.Shutdown 一样轮询
+func (s *Server) waitRequests(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for s.active.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// waitConns 等待 h2c 连接关闭, ctx 结束时关闭剩下的连接
+func (s *Server) waitConns(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		s.connMu.Lock()
+		n := len(s.conns)
+		s.connMu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			s.closeConns()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// closeConns 关闭 h2c hijack 之后仍然打开的连接
+func (s *Server) closeConns() {
+	s.connMu.Lock()
+	conns := make([]*trackedConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.connMu.Unlock()
+	for _, c := range conns {
+		_ = c.Close()
+	}
+}
+
+// trackedListener 记录明文连接, h2c hijack 之后 http.Server 不再管理它们
+type trackedListener struct {
+	net.Listener
+	s *Server
+}
+
+func (l *trackedListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	c := &trackedConn{Conn: conn, s: l.s}
+	l.s.connMu.Lock()
+	l.s.conns[c] = struct{}{}
+	l.s.connMu.Unlock()
+	return c, nil
+}
+
+type trackedConn struct {
+	net.Conn
+	s *Server
+}
+
+func (c *trackedConn) Close() error {
+	c.s.connMu.Lock()
+	delete(c.s.conns, c)
+	c.s.connMu.Unlock()
+	return c.Conn.Close()
+}
@@ -0,0 +1,246 @@
+package mux
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/cr-mao/lori/example/proto"
+	"github.com/cr-mao/lori/transport/grpc"
+	lorihttp "github.com/cr-mao/lori/transport/http"
+)
+
+type greeter struct {
+	proto.UnimplementedGreeterServer
+}
+
+func (g *greeter) SayHello(ctx context.Context, r *proto.HelloRequest) (*proto.HelloResponse, error) {
+	return &proto.HelloResponse{Message: "hello " + r.Name}, nil
+}
+
+func TestServer(t *testing.T) {
+	grpcSrv := grpc.NewServer()
+	httpSrv := lorihttp.NewServer(lorihttp.WithMode(gin.TestMode))
+	proto.RegisterGreeterServer(grpcSrv, &greeter{})
+	proto.RegisterGreeterHTTPServer(httpSrv, &greeter{})
+
+	srv := NewServer(grpcSrv, httpSrv, WithAddress("127.0.0.1:0"))
+	endpoints, err := srv.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endpoints) != 2 || endpoints[0].Scheme != "grpc" || endpoints[1].Scheme != "http" || endpoints[0].Host != endpoints[1].Host {
+		t.Fatalf("unexpected endpoints %v", endpoints)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	addr := endpoints[0].Host
+
+	conn, err := grpc.DialInsecure(context.Background(),
+		grpc.WithClientEndpoint("direct:///"+addr),
+		grpc.WithClientEnableTracing(false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	reply, err := proto.NewGreeterClient(conn).SayHello(context.Background(), &proto.HelloRequest{Name: "grpc"})
+	if err != nil || reply.Message != "hello grpc" {
+		t.Fatalf("unexpected grpc reply %v %v", reply, err)
+	}
+	health, err := grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil || health.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("unexpected health %v %v", health, err)
+	}
+
+	resp, err := http.Get("http://" + addr + "/v1/greeter/http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var httpReply proto.HelloResponse
+	if resp.StatusCode != http.StatusOK || protojson.Unmarshal(body, &httpReply) != nil || httpReply.Message != "hello http" {
+		t.Fatalf("unexpected http reply %d %s", resp.StatusCode, body)
+	}
+
+	if err = srv.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = http.Get("http://" + addr + "/v1/greeter/http"); err == nil {
+		t.Error("expect server stopped")
+	}
+}
+
+// blockingGreeter 收到请求后通知 started, 等到 release 关闭后返回
+type blockingGreeter struct {
+	proto.UnimplementedGreeterServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (g *blockingGreeter) SayHello(ctx context.Context, r *proto.HelloRequest) (*proto.HelloResponse, error) {
+	close(g.started)
+	<-g.release
+	return &proto.HelloResponse{Message: "hello " + r.Name}, nil
+}
+
+func TestServerStopWaitsGRPC(t *testing.T) {
+	g := &blockingGreeter{started: make(chan struct{}), release: make(chan struct{})}
+	grpcSrv := grpc.NewServer()
+	proto.RegisterGreeterServer(grpcSrv, g)
+	srv := NewServer(grpcSrv, lorihttp.NewServer(lorihttp.WithMode(gin.TestMode)), WithAddress("127.0.0.1:0"))
+	endpoints, err := srv.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+
+	conn, err := grpc.DialInsecure(context.Background(),
+		grpc.WithClientEndpoint("direct:///"+endpoints[0].Host),
+		grpc.WithClientEnableTracing(false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	replies := make(chan error, 1)
+	go func() {
+		_, err := proto.NewGreeterClient(conn).SayHello(context.Background(), &proto.HelloRequest{Name: "grpc"})
+		replies <- err
+	}()
+	<-g.started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- srv.Stop(context.Background()) }()
+	select {
+	case err = <-stopped:
+		t.Fatalf("expect Stop to wait for in-flight rpc, returned %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(g.release)
+	if err = <-replies; err != nil {
+		t.Errorf("expect in-flight rpc completed, got %v", err)
+	}
+	if err = <-stopped; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServerStopDeadline(t *testing.T) {
+	g := &blockingGreeter{started: make(chan struct{}), release: make(chan struct{})}
+	defer close(g.release)
+	grpcSrv := grpc.NewServer()
+	proto.RegisterGreeterServer(grpcSrv, g)
+	srv := NewServer(grpcSrv, lorihttp.NewServer(lorihttp.WithMode(gin.TestMode)), WithAddress("127.0.0.1:0"))
+	endpoints, err := srv.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+
+	conn, err := grpc.DialInsecure(context.Background(),
+		grpc.WithClientEndpoint("direct:///"+endpoints[0].Host),
+		grpc.WithClientEnableTracing(false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		_, _ = proto.NewGreeterClient(conn).SayHello(context.Background(), &proto.HelloRequest{Name: "grpc"})
+	}()
+	<-g.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err = srv.Stop(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expect %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func h2cClient() *http.Client {
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+}
+
+func TestServerStopWaitsH2C(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	httpSrv := lorihttp.NewServer(lorihttp.WithMode(gin.TestMode))
+	httpSrv.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	httpSrv.GET("/block", func(c *gin.Context) {
+		close(started)
+		<-release
+		c.String(http.StatusOK, "done")
+	})
+	srv := NewServer(grpc.NewServer(), httpSrv, WithAddress("127.0.0.1:0"))
+	endpoints, err := srv.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	base := "http://" + endpoints[1].Host
+
+	// 空闲的 h2c 连接
+	idle := h2cClient()
+	resp, err := idle.Get(base + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("expect h2c, got %s", resp.Proto)
+	}
+
+	replies := make(chan *http.Response, 1)
+	go func() {
+		resp, err := h2cClient().Get(base + "/block")
+		if err != nil {
+			t.Error(err)
+		}
+		replies <- resp
+	}()
+	<-started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- srv.Stop(context.Background()) }()
+	select {
+	case err = <-stopped:
+		t.Fatalf("expect Stop to wait for in-flight h2c request, returned %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	if resp = <-replies; resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expect in-flight h2c request completed, got %v", resp)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "done" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if err = <-stopped; err != nil {
+		t.Error(err)
+	}
+	srv.connMu.Lock()
+	n := len(srv.conns)
+	srv.connMu.Unlock()
+	if n != 0 {
+		t.Errorf("expect h2c conns closed after stop, %d still open", n)
+	}
+}
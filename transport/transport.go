@@ -16,6 +16,11 @@ type Endpointer interface {
 	Endpoint() (*url.URL, error)
 }
 
+// MultiEndpointer 一个 server 暴露多个端点, 比如 grpc 和 http 复用同一个端口时每种 scheme 一个
+type MultiEndpointer interface {
+	Endpoints() ([]*url.URL, error)
+}
+
 // Header is the storage medium used by a Header.
 type Header interface {
 	Get(key string) string